@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/akksell/rbn/internal/bill"
+	"github.com/akksell/rbn/internal/config"
+	"github.com/akksell/rbn/internal/filter"
+	"github.com/akksell/rbn/internal/gmail"
+	gmailapi "google.golang.org/api/gmail/v1"
+)
+
+// extractResult is what `rbn extract` reports for one file.
+type extractResult struct {
+	File      string          `json:"file"`
+	Forwarded string          `json:"forwardedBy,omitempty"`
+	Matched   bool            `json:"matched"`
+	Kind      bill.Kind       `json:"kind"`
+	Payment   *bill.Payment   `json:"payment,omitempty"`
+	Found     bool            `json:"found"`
+	Extracted *bill.Extracted `json:"extracted,omitempty"`
+}
+
+// runExtract implements `rbn extract [-config file] [-json] files...`: it runs
+// the filter and extractor chain over saved messages without any cloud access.
+func runExtract(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	configPath := fs.String("config", "configuration/dev.yaml", "YAML config with filters and billers")
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: rbn extract [-config file] [-json] file.eml|file.json ...")
+	}
+
+	cfg, err := config.LoadFile(*configPath)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	registry, err := bill.NewRegistry(cfg)
+	if err != nil {
+		return fmt.Errorf("extractor: %w", err)
+	}
+	chain := registry.Chain(cfg.Review.MinConfidence)
+
+	var results []extractResult
+	for _, path := range fs.Args() {
+		msg, err := gmail.ReadMessageFile(path)
+		if err != nil {
+			return err
+		}
+		forwardedBy := ""
+		if fwd, ok := gmail.Unforward(msg, nil); ok {
+			msg, forwardedBy = fwd.Original, fwd.By
+		}
+		res := extractResult{File: path, Forwarded: forwardedBy, Matched: filter.Match(&cfg.Filters, msg)}
+		src := offlineSource(msg)
+		res.Kind = bill.Classify(src)
+		if res.Kind == bill.KindPayment {
+			res.Payment, _ = bill.ExtractPayment(src, cfg.Location)
+		}
+		res.Extracted, res.Found = chain.Extract(src)
+		results = append(results, res)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	for _, res := range results {
+		printResult(stdout, res, cfg)
+	}
+	return nil
+}
+
+// offlineSource builds the extractor input from a saved message. Only
+// attachments stored inline in the file are available.
+func offlineSource(msg *gmailapi.Message) *bill.Source {
+	content := gmail.GetMessageContent(msg)
+	src := &bill.Source{Message: msg, HTML: content.HTML, Plain: content.Plain}
+	for _, a := range content.Attachments {
+		if a.Data != nil && bill.CanParse(a.MimeType, a.Filename) {
+			src.Attachments = append(src.Attachments, bill.Attachment{Filename: a.Filename, MimeType: a.MimeType, Data: a.Data})
+		}
+	}
+	return src
+}
+
+func printResult(w io.Writer, res extractResult, cfg *config.Config) {
+	fmt.Fprintln(w, res.File)
+	if res.Forwarded != "" {
+		fmt.Fprintf(w, "  forwarded:  by %s\n", res.Forwarded)
+	}
+	fmt.Fprintf(w, "  filter:     %s\n", matchWord(res.Matched))
+	fmt.Fprintf(w, "  kind:       %s\n", res.Kind)
+	if p := res.Payment; p != nil {
+		if !p.Amount.IsZero() {
+			fmt.Fprintf(w, "  paid:       %s\n", p.Amount.Format())
+		}
+		if !p.PaidOn.IsZero() {
+			fmt.Fprintf(w, "  paid on:    %s\n", p.PaidOn.Format("2006-01-02"))
+		}
+		if p.AccountNumber != "" {
+			fmt.Fprintf(w, "  account:    %s\n", p.AccountNumber)
+		}
+		return
+	}
+	if !res.Found {
+		fmt.Fprintln(w, "  extracted:  nothing")
+		return
+	}
+	x := res.Extracted
+	review := ""
+	if x.Confidence < cfg.Review.MinConfidence {
+		review = ", needs review"
+	}
+	fmt.Fprintf(w, "  extractor:  %s (confidence %.2f%s)\n", x.Extractor, x.Confidence, review)
+	fmt.Fprintf(w, "  biller:     %s\n", x.BillerCompany)
+	fmt.Fprintf(w, "  total:      %s\n", x.TotalAmount.Format())
+	if !x.DueDate.IsZero() {
+		fmt.Fprintf(w, "  due date:   %s\n", x.DueDate.Format("2006-01-02"))
+	}
+	if x.AccountNumber != "" {
+		fmt.Fprintf(w, "  account:    %s\n", x.AccountNumber)
+	}
+	if x.ServiceAddress != "" {
+		fmt.Fprintf(w, "  address:    %s\n", x.ServiceAddress)
+	}
+	if x.BillingPeriod != "" {
+		fmt.Fprintf(w, "  period:     %s\n", x.BillingPeriod)
+	}
+	if !x.PeriodStart.IsZero() {
+		fmt.Fprintf(w, "  service:    %s to %s\n", x.PeriodStart.Format("2006-01-02"), x.PeriodEnd.Format("2006-01-02"))
+	}
+	for _, u := range x.Usage {
+		fmt.Fprintf(w, "  usage:      %g %s\n", u.Quantity, u.Unit)
+	}
+	for _, li := range x.LineItems {
+		fmt.Fprintf(w, "  line item:  %-30s %s\n", li.Description, li.Amount.Format())
+	}
+	fmt.Fprintln(w, "  evidence:")
+	for _, ev := range x.Evidence {
+		fmt.Fprintf(w, "    %-8s [%s] %s\n", ev.Field, ev.Source, strings.ReplaceAll(ev.Text, "\t", " | "))
+	}
+}
+
+func matchWord(ok bool) string {
+	if ok {
+		return "match"
+	}
+	return "no match"
+}
+
+// extractMain runs the extract subcommand and exits.
+func extractMain(args []string) {
+	if err := runExtract(args, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "extract: %v\n", err)
+		os.Exit(1)
+	}
+}
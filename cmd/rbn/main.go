@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // household time zone lookup in the distroless image
 
 	"cloud.google.com/go/firestore"
 	"github.com/akksell/rbn/internal/bill"
@@ -19,6 +20,11 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "extract" {
+		extractMain(os.Args[2:])
+		return
+	}
+
 	ctx := context.Background()
 	cfg, err := config.Load(ctx)
 	if err != nil {
@@ -33,12 +39,25 @@ func main() {
 
 	st := store.New(fsClient)
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate-money" {
+		n, err := st.MigrateMoney(ctx)
+		if err != nil {
+			log.Fatalf("migrate money: %v", err)
+		}
+		log.Printf("migrated %d bills to minor-unit amounts", n)
+		return
+	}
+
 	gmailClient, err := gmail.NewClient(ctx, cfg.GmailInboxUser)
 	if err != nil {
 		log.Fatalf("gmail: %v", err)
 	}
 
-	extractor := bill.DefaultExtractor()
+	registry, err := bill.NewRegistry(cfg)
+	if err != nil {
+		log.Fatalf("extractor: %v", err)
+	}
+	extractor := registry.Chain(cfg.Review.MinConfidence)
 	sender := notify.NewSender(cfg, gmailClient)
 
 	srv, err := server.New(cfg, st, gmailClient, extractor, sender)
@@ -0,0 +1,33 @@
+package bill
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// defaultAccountRegex captures account numbers, including masked ones
+	// such as "XXXX-XXXX-4821" or "****4821".
+	defaultAccountRegex = regexp.MustCompile(`(?i)account\s*(?:number|no\.?|#)[:\s#]*([X*•\d][X*•\d -]{2,}\d)`)
+	// defaultAddressRegex captures the rest of a "Service address:" line or cell.
+	defaultAddressRegex = regexp.MustCompile(`(?i)service\s+(?:address|location)[:\s]*([^\n\t]+)`)
+)
+
+// MaskAccount reduces an account number to its last four digits, e.g.
+// "1234-5678-4821" and "XXXX-4821" both become "****4821".
+func MaskAccount(account string) string {
+	var digits strings.Builder
+	for _, r := range account {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	if d == "" {
+		return ""
+	}
+	if len(d) > 4 {
+		d = d[len(d)-4:]
+	}
+	return "****" + d
+}
@@ -0,0 +1,142 @@
+package bill
+
+import (
+	"sort"
+
+	"github.com/akksell/rbn/internal/config"
+)
+
+// Priorities of the built-in extractors; lower runs first.
+const (
+	PrioritySchemaOrg  = 10
+	PriorityStructured = 15 // e-invoice and CSV attachments
+	PriorityBiller     = 20
+	PriorityAttachment = 30
+	PriorityGeneric    = 100
+)
+
+// Registry collects extractors with their priorities.
+type Registry struct {
+	entries []registered
+}
+
+type registered struct {
+	priority int
+	ext      Extractor
+}
+
+// NewRegistry returns a registry with the built-in extractors: schema.org
+// markup, e-invoice and CSV attachments, per-biller patterns, attachment
+// patterns and the generic fallback.
+func NewRegistry(cfg *config.Config) (*Registry, error) {
+	biller, err := NewBillerExtractor(cfg.Billers, cfg.Location)
+	if err != nil {
+		return nil, err
+	}
+	csvExtractor, err := NewCSVExtractor(cfg.Billers, cfg.Location)
+	if err != nil {
+		return nil, err
+	}
+	generic := DefaultExtractor()
+	if cfg.Location != nil {
+		generic.Location = cfg.Location
+	}
+
+	r := &Registry{}
+	r.Register(PrioritySchemaOrg, NewSchemaOrgExtractor(cfg.Location))
+	r.Register(PriorityStructured, NewEInvoiceExtractor(cfg.Location))
+	r.Register(PriorityStructured, csvExtractor)
+	r.Register(PriorityBiller, biller)
+	r.Register(PriorityAttachment, NewAttachmentExtractor(cfg.Location))
+	r.Register(PriorityGeneric, generic)
+	return r, nil
+}
+
+// Register adds an extractor. Extractors with equal priority run in the order registered.
+func (r *Registry) Register(priority int, e Extractor) {
+	r.entries = append(r.entries, registered{priority: priority, ext: e})
+	sort.SliceStable(r.entries, func(i, j int) bool { return r.entries[i].priority < r.entries[j].priority })
+}
+
+// Chain returns a chain over the registered extractors that accepts the first
+// result with at least minConfidence.
+func (r *Registry) Chain(minConfidence float64) *Chain {
+	c := &Chain{MinConfidence: minConfidence}
+	for _, e := range r.entries {
+		c.extractors = append(c.extractors, e.ext)
+	}
+	return c
+}
+
+// Chain runs extractors in priority order.
+type Chain struct {
+	extractors []Extractor
+	// MinConfidence is the confidence at which a result is accepted without
+	// consulting lower-priority extractors.
+	MinConfidence float64
+}
+
+// Name implements Extractor.
+func (c *Chain) Name() string { return "chain" }
+
+// Extract returns the first result whose confidence reaches MinConfidence,
+// or the most confident result if none does. Fields the chosen result is
+// missing are filled in from the other results, in priority order.
+func (c *Chain) Extract(src *Source) (*Extracted, bool) {
+	var results []*Extracted
+	var best *Extracted
+	for _, e := range c.extractors {
+		out, ok := e.Extract(src)
+		if !ok {
+			continue
+		}
+		results = append(results, out)
+		if best == nil || (best.Confidence < c.MinConfidence && out.Confidence > best.Confidence) {
+			best = out
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	for _, other := range results {
+		if other != best {
+			fillMissing(best, other)
+		}
+	}
+	return best, true
+}
+
+// fillMissing copies the optional fields dst lacks from src, with their evidence.
+func fillMissing(dst, src *Extracted) {
+	if dst.DueDate.IsZero() && !src.DueDate.IsZero() {
+		dst.DueDate = src.DueDate
+		dst.Evidence = append(dst.Evidence, evidenceFor(src, "dueDate")...)
+	}
+	if dst.AccountNumber == "" && src.AccountNumber != "" {
+		dst.AccountNumber = src.AccountNumber
+		dst.Evidence = append(dst.Evidence, evidenceFor(src, "account")...)
+	}
+	if dst.ServiceAddress == "" && src.ServiceAddress != "" {
+		dst.ServiceAddress = src.ServiceAddress
+		dst.Evidence = append(dst.Evidence, evidenceFor(src, "address")...)
+	}
+	if dst.BillingPeriod == "" && src.BillingPeriod != "" {
+		dst.BillingPeriod = src.BillingPeriod
+		dst.PeriodStart, dst.PeriodEnd = src.PeriodStart, src.PeriodEnd
+		dst.Evidence = append(dst.Evidence, evidenceFor(src, "period")...)
+	}
+	if len(dst.Usage) == 0 && len(src.Usage) > 0 {
+		dst.Usage = src.Usage
+		dst.Evidence = append(dst.Evidence, evidenceFor(src, "usage")...)
+	}
+}
+
+func evidenceFor(e *Extracted, field string) []Evidence {
+	var out []Evidence
+	for _, ev := range e.Evidence {
+		if ev.Field == field {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
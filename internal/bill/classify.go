@@ -0,0 +1,89 @@
+package bill
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/akksell/rbn/internal/money"
+)
+
+// Kind is what a message from a biller is about.
+type Kind string
+
+// Message kinds returned by Classify.
+const (
+	KindBill       Kind = "bill"       // a new statement
+	KindReminder   Kind = "reminder"   // a reminder about a statement already sent
+	KindPayment    Kind = "payment"    // confirmation that the biller received a payment
+	KindCorrection Kind = "correction" // a corrected statement replacing an earlier one
+)
+
+var (
+	paymentPhrase    = regexp.MustCompile(`(?i)\b(?:we(?:'ve| have)? received your payment|thank you for your (?:recent )?payment|payment (?:has been |was )?(?:received|processed|posted|confirmed|successful)|payment confirmation|auto-?pay(?:ment)? (?:was |has been )?(?:processed|completed|successful))\b`)
+	correctionPhrase = regexp.MustCompile(`(?i)\b(?:corrected|revised|amended|updated) (?:bill|statement|invoice)\b|\b(?:bill|statement|invoice) correction\b|\bplease disregard (?:our|the|your) previous\b`)
+	reminderPhrase   = regexp.MustCompile(`(?i)\breminder\b|\b(?:is|are) (?:now )?(?:due|past due) (?:soon|today|tomorrow|in \d+ days?)\b|\bpast due\b|\bhas not (?:yet )?been (?:paid|received)\b`)
+	// A statement's account summary often thanks the customer for last
+	// month's payment; these phrases mark the body as a bill regardless.
+	amountOwedPhrase = regexp.MustCompile(`(?i)\b(?:amount|balance|total) due\b|\bdue (?:date|by|on)\b|\bplease pay\b`)
+
+	paymentAmountRegex = regexp.MustCompile(`(?i)(?:payment(?:\s+amount)?|amount\s+(?:paid|received)|paid)\s*(?:of|:)?\s*` + amountPattern)
+	paymentDateRegex   = regexp.MustCompile(`(?i)(?:payment|paid|processed|posted)\s+(?:date|on)[:\s]*` + datePattern)
+)
+
+// Classify decides what kind of message src is from its subject and body.
+// The subject is trusted first; the body only decides when the subject says
+// nothing, and a body that asks for money is never a payment confirmation.
+func Classify(src *Source) Kind {
+	if k, ok := classifyText(getHeader(src.Message, "Subject"), false); ok {
+		return k
+	}
+	body := src.BodyText()
+	if k, ok := classifyText(body, amountOwedPhrase.MatchString(body)); ok {
+		return k
+	}
+	return KindBill
+}
+
+func classifyText(s string, owed bool) (Kind, bool) {
+	switch {
+	case correctionPhrase.MatchString(s):
+		return KindCorrection, true
+	case paymentPhrase.MatchString(s) && !owed:
+		return KindPayment, true
+	case reminderPhrase.MatchString(s):
+		return KindReminder, true
+	}
+	return "", false
+}
+
+// Payment is what a payment confirmation says was paid.
+type Payment struct {
+	Amount        money.Money `json:"amount"` // zero if the confirmation doesn't state it
+	PaidOn        time.Time   `json:"paidOn"` // zero if not stated
+	AccountNumber string      `json:"accountNumber,omitempty"`
+	Evidence      []Evidence  `json:"evidence,omitempty"`
+}
+
+// ExtractPayment reads the amount, date and account from a payment
+// confirmation. It reports false if none of them is found.
+func ExtractPayment(src *Source, loc *time.Location) (*Payment, bool) {
+	texts := src.texts(ScopeAll)
+	p := &Payment{}
+	if h, ok := find(paymentAmountRegex, texts); ok {
+		if m, err := parseAmount(h.Value, h.Match, "USD"); err == nil && !m.IsZero() {
+			p.Amount = m
+			p.Evidence = append(p.Evidence, h.evidence("total"))
+		}
+	}
+	if h, ok := find(paymentDateRegex, texts); ok {
+		if t, err := ParseDate(h.Value, loc); err == nil {
+			p.PaidOn = t
+			p.Evidence = append(p.Evidence, h.evidence("paidOn"))
+		}
+	}
+	if h, ok := find(defaultAccountRegex, texts); ok {
+		p.AccountNumber = h.Value
+		p.Evidence = append(p.Evidence, h.evidence("account"))
+	}
+	return p, len(p.Evidence) > 0
+}
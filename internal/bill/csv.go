@@ -0,0 +1,192 @@
+package bill
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/akksell/rbn/internal/config"
+	"github.com/akksell/rbn/internal/money"
+)
+
+// csvConfidence is the confidence of a total read from a configured CSV layout.
+const csvConfidence = 0.9
+
+// CSVExtractor reads CSV statement attachments laid out as configured in the
+// sending biller's profile.
+type CSVExtractor struct {
+	// Location is the household time zone due dates are resolved in.
+	Location *time.Location
+	// Profiles are the biller profiles that have a CSV layout.
+	Profiles []Profile
+}
+
+// NewCSVExtractor returns an extractor for the CSV layouts in billers.
+func NewCSVExtractor(billers []config.BillerProfile, loc *time.Location) (*CSVExtractor, error) {
+	profiles, err := CompileProfiles(billers)
+	if err != nil {
+		return nil, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	e := &CSVExtractor{Location: loc}
+	for _, p := range profiles {
+		if p.CSV != nil {
+			e.Profiles = append(e.Profiles, p)
+		}
+	}
+	return e, nil
+}
+
+// Name implements Extractor.
+func (e *CSVExtractor) Name() string { return "csv" }
+
+// Extract implements Extractor.
+func (e *CSVExtractor) Extract(src *Source) (*Extracted, bool) {
+	from := getHeader(src.Message, "From")
+	var profile *Profile
+	for i := range e.Profiles {
+		p := &e.Profiles[i]
+		if (src.Biller != "" && strings.EqualFold(p.Name, src.Biller)) || p.matches(from) {
+			profile = p
+			break
+		}
+	}
+	if profile == nil {
+		return nil, false
+	}
+
+	for _, a := range src.Attachments {
+		if !isCSV(a.MimeType, a.Filename) {
+			continue
+		}
+		if profile.CSVFilename != nil && !profile.CSVFilename.MatchString(a.Filename) {
+			continue
+		}
+		out, err := e.parse(a, profile)
+		if err != nil {
+			continue
+		}
+		out.BillerCompany = firstNonEmpty(src.Biller, profile.Name, SenderName(from))
+		return out, true
+	}
+	return nil, false
+}
+
+func isCSV(mimeType, filename string) bool {
+	mimeType = strings.ToLower(mimeType)
+	return mimeType == "text/csv" || mimeType == "application/csv" ||
+		strings.HasSuffix(strings.ToLower(filename), ".csv")
+}
+
+// checkCSVLayout validates a configured layout.
+func checkCSVLayout(l *config.CSVLayout) error {
+	if l.DescriptionColumn == "" || l.AmountColumn == "" {
+		return errors.New("descriptionColumn and amountColumn are required")
+	}
+	if l.Delimiter != "" && utf8.RuneCountInString(l.Delimiter) != 1 {
+		return fmt.Errorf("delimiter %q is not a single character", l.Delimiter)
+	}
+	return nil
+}
+
+func (e *CSVExtractor) parse(a Attachment, p *Profile) (*Extracted, error) {
+	layout := p.CSV
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(a.Data, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	if layout.Delimiter != "" {
+		r.Comma, _ = utf8.DecodeRuneInString(layout.Delimiter)
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the header row and the configured columns.
+	names := []string{layout.DescriptionColumn, layout.AmountColumn, layout.DueDateColumn, layout.AccountColumn}
+	cols := []int{-1, -1, -1, -1}
+	header := -1
+	for i, row := range rows {
+		for j, name := range names {
+			cols[j] = -1
+			for k, cell := range row {
+				if name != "" && strings.EqualFold(strings.TrimSpace(cell), name) {
+					cols[j] = k
+					break
+				}
+			}
+		}
+		if cols[0] >= 0 && cols[1] >= 0 {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, errors.New("csv: header row not found")
+	}
+	descCol, amountCol, dueCol, accountCol := cols[0], cols[1], cols[2], cols[3]
+	cell := func(row []string, col int) string {
+		if col < 0 || col >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[col])
+	}
+	currency := p.Currency
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	ev := func(field string, row []string) Evidence {
+		return Evidence{Field: field, Text: strings.Join(row, "\t"), Source: a.Filename}
+	}
+
+	out := &Extracted{Extractor: e.Name(), Confidence: csvConfidence}
+	var sum money.Money
+	var total *money.Money
+	for _, row := range rows[header+1:] {
+		desc, raw := cell(row, descCol), cell(row, amountCol)
+		if due := cell(row, dueCol); due != "" && out.DueDate.IsZero() {
+			if t, err := ParseDate(due, e.Location); err == nil {
+				out.DueDate = t
+				out.Evidence = append(out.Evidence, ev("dueDate", row))
+			}
+		}
+		if acct := cell(row, accountCol); acct != "" && out.AccountNumber == "" {
+			out.AccountNumber = acct
+			out.Evidence = append(out.Evidence, ev("account", row))
+		}
+		if raw == "" {
+			continue
+		}
+		m, err := parseAmount(raw, raw, currency)
+		if err != nil {
+			continue
+		}
+		if layout.TotalLabel != "" && strings.EqualFold(desc, layout.TotalLabel) {
+			total = &m
+			out.Evidence = append(out.Evidence, ev("total", row))
+			continue
+		}
+		if sum.Currency == "" {
+			sum = money.New(0, m.Currency)
+		}
+		sum = sum.Add(m)
+		out.LineItems = append(out.LineItems, LineItem{Description: desc, Amount: m})
+	}
+
+	switch {
+	case total != nil:
+		out.TotalAmount = *total
+	case layout.TotalLabel == "" && len(out.LineItems) > 0:
+		out.TotalAmount = sum
+		out.Evidence = append(out.Evidence, Evidence{Field: "total", Text: fmt.Sprintf("sum of %d line items", len(out.LineItems)), Source: a.Filename})
+	default:
+		return nil, errors.New("csv: no total")
+	}
+	return out, nil
+}
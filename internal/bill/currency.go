@@ -0,0 +1,76 @@
+package bill
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/akksell/rbn/internal/money"
+)
+
+// amountPattern captures an amount with an optional currency symbol or ISO
+// code on either side, in US or European digit grouping or ungrouped. The
+// grouped form needs a separator and comes first: RE2 takes the first
+// alternative that matches, so "1,234.56" must not stop at "1,23", and an
+// optional separator would cut "1234.56" short at "123".
+const amountPattern = `((?:[A-Z]{1,2}\$|[$€£¥₹]|\b(?:USD|EUR|GBP|CAD|AUD|NZD|CHF|JPY|MXN|INR|SEK|NOK|DKK)\s?)?` +
+	`\s*(?:\d{1,3}(?:[.,']\d{3})+|\d+)(?:[.,]\d{1,2})?` +
+	`(?:\s?(?:€|\b(?:USD|EUR|GBP|CAD|AUD|NZD|CHF|JPY|MXN|INR|SEK|NOK|DKK)\b))?)`
+
+var (
+	isoCode       = regexp.MustCompile(`\b(USD|EUR|GBP|CAD|AUD|NZD|CHF|JPY|MXN|INR|SEK|NOK|DKK)\b`)
+	dollarPrefix  = regexp.MustCompile(`\b(US|CA|C|AU|A|NZ|MX)\$`)
+	amountDigits  = regexp.MustCompile(`-?\d[\d.,']*`)
+	dollarSymbols = map[string]string{
+		"US": "USD", "CA": "CAD", "C": "CAD", "AU": "AUD", "A": "AUD", "NZ": "NZD", "MX": "MXN",
+	}
+	otherSymbols = map[string]string{"€": "EUR", "£": "GBP", "¥": "JPY", "₹": "INR"}
+)
+
+// detectCurrency returns the ISO code named by a currency symbol or code in s.
+// A bare "$" is read as fallback if that is a dollar currency (e.g. CAD for a
+// Canadian biller's "$45.00"), else as USD.
+func detectCurrency(s, fallback string) (string, bool) {
+	upper := strings.ToUpper(s)
+	if m := isoCode.FindString(upper); m != "" {
+		return m, true
+	}
+	if m := dollarPrefix.FindStringSubmatch(upper); m != nil {
+		return dollarSymbols[m[1]], true
+	}
+	for sym, code := range otherSymbols {
+		if strings.Contains(s, sym) {
+			return code, true
+		}
+	}
+	if strings.Contains(s, "$") {
+		if isDollar(fallback) {
+			return fallback, true
+		}
+		return "USD", true
+	}
+	return "", false
+}
+
+// isDollar reports whether currency is written with a bare "$" at home.
+func isDollar(currency string) bool {
+	for _, code := range dollarSymbols {
+		if code == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAmount reads an amount such as "€1.234,56" or "1,234.56 CAD".
+// The currency comes from raw, then the full pattern match, then fallback.
+func parseAmount(raw, match, fallback string) (money.Money, error) {
+	currency, ok := detectCurrency(raw, fallback)
+	if !ok {
+		currency, ok = detectCurrency(match, fallback)
+	}
+	if !ok {
+		currency = fallback
+	}
+	digits := amountDigits.FindString(raw)
+	return money.Parse(digits, currency)
+}
@@ -0,0 +1,95 @@
+package bill
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// datePattern matches the date formats billers commonly use:
+// ISO (2026-10-05), US numeric (10/05/2026, 10-5-26) and written months
+// (Oct 5, 2026; October 5th 2026; 5 October 2026).
+const datePattern = `(\d{4}-\d{1,2}-\d{1,2}` +
+	`|\d{1,2}[/-]\d{1,2}[/-](?:\d{4}|\d{2})` +
+	`|[A-Za-z]{3,9}\.?\s+\d{1,2}(?:st|nd|rd|th)?,?\s+\d{4}` +
+	`|\d{1,2}(?:st|nd|rd|th)?\s+[A-Za-z]{3,9}\.?,?\s+\d{4})`
+
+// defaultDueDateRegex finds a date introduced by a due-date phrase such as
+// "due by", "due on", "payment due" or "due date".
+var defaultDueDateRegex = regexp.MustCompile(`(?i)(?:payment\s+due(?:\s+(?:date|by|on))?|due\s+(?:date|by|on)|pay\s+by)[:\s]*(?:on\s+)?` + datePattern)
+
+var (
+	ordinalSuffix = regexp.MustCompile(`(?i)(\d)(?:st|nd|rd|th)\b`)
+	dateSpaces    = regexp.MustCompile(`\s+`)
+)
+
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-1-2",
+	"01/02/2006",
+	"1/2/2006",
+	"01/02/06",
+	"1/2/06",
+	"01-02-2006",
+	"1-2-2006",
+	"01-02-06",
+	"1-2-06",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"January 2, 2006",
+	"January 2 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+}
+
+// ParseDate parses a date in any of the supported formats as midnight in loc.
+// Numeric dates with slashes or dashes are read month first (US order).
+// A nil loc means UTC.
+func ParseDate(s string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	norm := strings.TrimSpace(s)
+	norm = ordinalSuffix.ReplaceAllString(norm, "$1")
+	norm = strings.ReplaceAll(norm, ".", "")
+	norm = dateSpaces.ReplaceAllString(norm, " ")
+	norm = strings.TrimSuffix(norm, ",")
+	norm = normalizeMonth(norm)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, norm, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+// normalizeMonth rewrites month names to the title-cased forms time.Parse expects
+// and maps "Sept" to "Sep".
+func normalizeMonth(s string) string {
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		word := strings.TrimSuffix(f, ",")
+		if len(word) < 3 || !isLetters(word) {
+			continue
+		}
+		word = strings.ToUpper(word[:1]) + strings.ToLower(word[1:])
+		if word == "Sept" {
+			word = "Sep"
+		}
+		if strings.HasSuffix(f, ",") {
+			word += ","
+		}
+		fields[i] = word
+	}
+	return strings.Join(fields, " ")
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package bill
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"github.com/akksell/rbn/internal/money"
+)
+
+// einvoiceConfidence is the confidence of a total read from an e-invoice.
+const einvoiceConfidence = 0.95
+
+// LineItem is one charge on a bill.
+type LineItem struct {
+	Description string      `json:"description"`
+	Amount      money.Money `json:"amount"`
+}
+
+// EInvoiceExtractor reads UBL 2.x and UN/CEFACT Cross Industry Invoice (CII)
+// XML attachments.
+type EInvoiceExtractor struct {
+	// Location is the household time zone dates are resolved in.
+	Location *time.Location
+}
+
+// NewEInvoiceExtractor returns an e-invoice extractor resolving dates in loc.
+func NewEInvoiceExtractor(loc *time.Location) *EInvoiceExtractor {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &EInvoiceExtractor{Location: loc}
+}
+
+// Name implements Extractor.
+func (e *EInvoiceExtractor) Name() string { return "e-invoice" }
+
+// Extract implements Extractor. The first XML attachment that parses as an
+// invoice with a payable amount wins.
+func (e *EInvoiceExtractor) Extract(src *Source) (*Extracted, bool) {
+	for _, a := range src.Attachments {
+		if !isXML(a.MimeType, a.Filename) {
+			continue
+		}
+		var out *Extracted
+		var ok bool
+		switch xmlRoot(a.Data) {
+		case "Invoice":
+			out, ok = e.fromUBL(a)
+		case "CrossIndustryInvoice":
+			out, ok = e.fromCII(a)
+		}
+		if !ok {
+			continue
+		}
+		out.Extractor = e.Name()
+		out.Confidence = einvoiceConfidence
+		if out.BillerCompany == "" {
+			out.BillerCompany = SenderName(getHeader(src.Message, "From"))
+		}
+		if src.Biller != "" {
+			out.BillerCompany = src.Biller
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func isXML(mimeType, filename string) bool {
+	mimeType = strings.ToLower(mimeType)
+	return mimeType == "application/xml" || mimeType == "text/xml" ||
+		strings.HasSuffix(strings.ToLower(filename), ".xml")
+}
+
+// xmlRoot returns the local name of the document element.
+func xmlRoot(data []byte) string {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return ""
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name.Local
+		}
+	}
+}
+
+// xmlAmount is an amount element with an optional currencyID attribute.
+type xmlAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"currencyID,attr"`
+}
+
+func (a xmlAmount) money(currency string) (money.Money, bool) {
+	if a.Currency != "" {
+		currency = a.Currency
+	}
+	if strings.TrimSpace(a.Value) == "" || currency == "" {
+		return money.Money{}, false
+	}
+	m, err := money.Parse(strings.TrimSpace(a.Value), strings.ToUpper(currency))
+	return m, err == nil
+}
+
+// ublInvoice is the subset of a UBL Invoice the extractor reads. Element
+// names are matched without namespaces.
+type ublInvoice struct {
+	DueDate        string    `xml:"DueDate"`
+	PaymentDueDate string    `xml:"PaymentMeans>PaymentDueDate"`
+	Currency       string    `xml:"DocumentCurrencyCode"`
+	SupplierName   string    `xml:"AccountingSupplierParty>Party>PartyName>Name"`
+	SupplierLegal  string    `xml:"AccountingSupplierParty>Party>PartyLegalEntity>RegistrationName"`
+	CustomerID     string    `xml:"AccountingCustomerParty>Party>PartyIdentification>ID"`
+	PeriodStart    string    `xml:"InvoicePeriod>StartDate"`
+	PeriodEnd      string    `xml:"InvoicePeriod>EndDate"`
+	Payable        xmlAmount `xml:"LegalMonetaryTotal>PayableAmount"`
+	Lines          []struct {
+		Amount      xmlAmount `xml:"LineExtensionAmount"`
+		Name        string    `xml:"Item>Name"`
+		Description string    `xml:"Item>Description"`
+	} `xml:"InvoiceLine"`
+}
+
+func (e *EInvoiceExtractor) fromUBL(a Attachment) (*Extracted, bool) {
+	var inv ublInvoice
+	if err := xml.Unmarshal(a.Data, &inv); err != nil {
+		return nil, false
+	}
+	total, ok := inv.Payable.money(inv.Currency)
+	if !ok {
+		return nil, false
+	}
+	ev := func(field, text string) Evidence { return Evidence{Field: field, Text: text, Source: a.Filename} }
+
+	out := &Extracted{TotalAmount: total}
+	out.Evidence = append(out.Evidence, ev("total", "PayableAmount: "+strings.TrimSpace(inv.Payable.Value)+" "+total.Currency))
+	out.BillerCompany = firstNonEmpty(inv.SupplierName, inv.SupplierLegal)
+	if out.BillerCompany != "" {
+		out.Evidence = append(out.Evidence, ev("biller", "AccountingSupplierParty: "+out.BillerCompany))
+	}
+	if due := firstNonEmpty(inv.DueDate, inv.PaymentDueDate); due != "" {
+		if t, err := ParseDate(due, e.Location); err == nil {
+			out.DueDate = t
+			out.Evidence = append(out.Evidence, ev("dueDate", "DueDate: "+due))
+		}
+	}
+	if inv.CustomerID != "" {
+		out.AccountNumber = strings.TrimSpace(inv.CustomerID)
+		out.Evidence = append(out.Evidence, ev("account", "AccountingCustomerParty ID: "+out.AccountNumber))
+	}
+	start, err1 := ParseDate(inv.PeriodStart, e.Location)
+	end, err2 := ParseDate(inv.PeriodEnd, e.Location)
+	if err1 == nil && err2 == nil {
+		out.BillingPeriod = inv.PeriodStart + "/" + inv.PeriodEnd
+		out.PeriodStart, out.PeriodEnd = start, end
+		out.Evidence = append(out.Evidence, ev("period", "InvoicePeriod: "+out.BillingPeriod))
+	}
+	for _, l := range inv.Lines {
+		m, ok := l.Amount.money(inv.Currency)
+		if !ok {
+			continue
+		}
+		out.LineItems = append(out.LineItems, LineItem{Description: firstNonEmpty(l.Name, l.Description), Amount: m})
+	}
+	return out, true
+}
+
+// ciiInvoice is the subset of a CII CrossIndustryInvoice the extractor reads.
+type ciiInvoice struct {
+	Lines []struct {
+		Name   string    `xml:"SpecifiedTradeProduct>Name"`
+		Amount xmlAmount `xml:"SpecifiedLineTradeSettlement>SpecifiedTradeSettlementLineMonetarySummation>LineTotalAmount"`
+	} `xml:"SupplyChainTradeTransaction>IncludedSupplyChainTradeLineItem"`
+	Seller     string `xml:"SupplyChainTradeTransaction>ApplicableHeaderTradeAgreement>SellerTradeParty>Name"`
+	BuyerID    string `xml:"SupplyChainTradeTransaction>ApplicableHeaderTradeAgreement>BuyerTradeParty>ID"`
+	Settlement struct {
+		Currency    string    `xml:"InvoiceCurrencyCode"`
+		Due         string    `xml:"SpecifiedTradePaymentTerms>DueDateDateTime>DateTimeString"`
+		PeriodStart string    `xml:"BillingSpecifiedPeriod>StartDateTime>DateTimeString"`
+		PeriodEnd   string    `xml:"BillingSpecifiedPeriod>EndDateTime>DateTimeString"`
+		DuePayable  xmlAmount `xml:"SpecifiedTradeSettlementHeaderMonetarySummation>DuePayableAmount"`
+		GrandTotal  xmlAmount `xml:"SpecifiedTradeSettlementHeaderMonetarySummation>GrandTotalAmount"`
+	} `xml:"SupplyChainTradeTransaction>ApplicableHeaderTradeSettlement"`
+}
+
+func (e *EInvoiceExtractor) fromCII(a Attachment) (*Extracted, bool) {
+	var inv ciiInvoice
+	if err := xml.Unmarshal(a.Data, &inv); err != nil {
+		return nil, false
+	}
+	set := inv.Settlement
+	amount, label := set.DuePayable, "DuePayableAmount"
+	if strings.TrimSpace(amount.Value) == "" {
+		amount, label = set.GrandTotal, "GrandTotalAmount"
+	}
+	total, ok := amount.money(set.Currency)
+	if !ok {
+		return nil, false
+	}
+	ev := func(field, text string) Evidence { return Evidence{Field: field, Text: text, Source: a.Filename} }
+
+	out := &Extracted{TotalAmount: total, BillerCompany: strings.TrimSpace(inv.Seller)}
+	out.Evidence = append(out.Evidence, ev("total", label+": "+strings.TrimSpace(amount.Value)+" "+total.Currency))
+	if out.BillerCompany != "" {
+		out.Evidence = append(out.Evidence, ev("biller", "SellerTradeParty: "+out.BillerCompany))
+	}
+	if t, err := parseCIIDate(set.Due, e.Location); err == nil {
+		out.DueDate = t
+		out.Evidence = append(out.Evidence, ev("dueDate", "DueDateDateTime: "+set.Due))
+	}
+	if inv.BuyerID != "" {
+		out.AccountNumber = strings.TrimSpace(inv.BuyerID)
+		out.Evidence = append(out.Evidence, ev("account", "BuyerTradeParty ID: "+out.AccountNumber))
+	}
+	start, err1 := parseCIIDate(set.PeriodStart, e.Location)
+	end, err2 := parseCIIDate(set.PeriodEnd, e.Location)
+	if err1 == nil && err2 == nil {
+		out.BillingPeriod = start.Format("2006-01-02") + "/" + end.Format("2006-01-02")
+		out.PeriodStart, out.PeriodEnd = start, end
+		out.Evidence = append(out.Evidence, ev("period", "BillingSpecifiedPeriod: "+set.PeriodStart+"-"+set.PeriodEnd))
+	}
+	for _, l := range inv.Lines {
+		m, ok := l.Amount.money(set.Currency)
+		if !ok {
+			continue
+		}
+		out.LineItems = append(out.LineItems, LineItem{Description: strings.TrimSpace(l.Name), Amount: m})
+	}
+	return out, true
+}
+
+// parseCIIDate reads a CII date (format 102, YYYYMMDD), falling back to ParseDate.
+func parseCIIDate(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation("20060102", s, loc); err == nil {
+		return t, nil
+	}
+	return ParseDate(s, loc)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package bill
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Evidence is the text an extracted field was read from.
+type Evidence struct {
+	Field  string `json:"field"`  // total, dueDate, biller, account, address, period, usage, paidOn
+	Text   string `json:"text"`   // the line containing the match
+	Source string `json:"source"` // "body", the attachment filename, or "schema.org" for markup
+}
+
+// sourceText is one searchable text of a Source.
+type sourceText struct {
+	Name string
+	Text string
+}
+
+// hit is a pattern match in a source text.
+type hit struct {
+	Value  string // first capture group, or the whole match if there is none
+	Match  string
+	Line   string
+	Source string
+}
+
+func (h hit) evidence(field string) Evidence {
+	return Evidence{Field: field, Text: h.Line, Source: h.Source}
+}
+
+// find returns the first match of re in the first text that has one.
+func find(re *regexp.Regexp, texts []sourceText) (hit, bool) {
+	if re == nil {
+		return hit{}, false
+	}
+	for _, t := range texts {
+		loc := re.FindStringSubmatchIndex(t.Text)
+		if loc == nil {
+			continue
+		}
+		h := hit{Match: t.Text[loc[0]:loc[1]], Line: lineAround(t.Text, loc[0], loc[1]), Source: t.Name}
+		h.Value = h.Match
+		if len(loc) > 3 && loc[2] >= 0 {
+			h.Value = t.Text[loc[2]:loc[3]]
+		}
+		h.Value = strings.TrimSpace(h.Value)
+		return h, true
+	}
+	return hit{}, false
+}
+
+// countDistinct returns how many different values re captures across texts.
+func countDistinct(re *regexp.Regexp, texts []sourceText) int {
+	seen := make(map[string]bool)
+	for _, t := range texts {
+		for _, m := range re.FindAllStringSubmatch(t.Text, -1) {
+			v := m[0]
+			if len(m) > 1 {
+				v = m[1]
+			}
+			seen[strings.TrimSpace(v)] = true
+		}
+	}
+	return len(seen)
+}
+
+// lineAround returns the full line(s) of text spanning [start, end).
+func lineAround(text string, start, end int) string {
+	if i := strings.LastIndexByte(text[:start], '\n'); i >= 0 {
+		start = i + 1
+	} else {
+		start = 0
+	}
+	if i := strings.IndexByte(text[end:], '\n'); i >= 0 {
+		end += i
+	} else {
+		end = len(text)
+	}
+	return strings.TrimSpace(text[start:end])
+}
+
+var (
+	strongTotalPhrase = regexp.MustCompile(`(?i)amount\s+due|total\s+(?:amount\s+)?due|new\s+balance|please\s+pay|total\s+charges`)
+	weakTotalPhrase   = regexp.MustCompile(`(?i)previous|prior|last\s+(?:bill|statement|payment)|payment\s+received|credit`)
+)
+
+// totalConfidence scores how likely the total hit is the amount due, from 0 to 1.
+// Profile patterns are trusted more than the generic pattern; phrases such as
+// "amount due" raise the score, while "previous balance" style lines and
+// several competing amounts lower it.
+func totalConfidence(h hit, fromProfile bool, candidates int) float64 {
+	score := 0.6
+	if fromProfile {
+		score = 0.85
+	}
+	if strongTotalPhrase.MatchString(h.Line) {
+		score += 0.2
+	}
+	if weakTotalPhrase.MatchString(h.Line) {
+		score -= 0.3
+	}
+	if candidates > 1 {
+		score -= 0.15
+	}
+	return clamp01(score)
+}
+
+func clamp01(f float64) float64 {
+	if f < 0 {
+		return 0
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+// findLineItems returns every description/amount match of re in the first
+// text that has any.
+func findLineItems(re *regexp.Regexp, texts []sourceText, currency string) []LineItem {
+	for _, t := range texts {
+		var items []LineItem
+		for _, m := range re.FindAllStringSubmatch(t.Text, -1) {
+			amount, err := parseAmount(m[2], m[0], currency)
+			if err != nil {
+				continue
+			}
+			items = append(items, LineItem{Description: strings.TrimSpace(m[1]), Amount: amount})
+		}
+		if len(items) > 0 {
+			return items
+		}
+	}
+	return nil
+}
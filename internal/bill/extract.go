@@ -1,65 +1,260 @@
 package bill
 
 import (
+	"net/mail"
 	"regexp"
+	"strings"
 	"time"
 
+	"github.com/akksell/rbn/internal/config"
+	"github.com/akksell/rbn/internal/money"
 	"google.golang.org/api/gmail/v1"
 )
 
 // Extracted holds parsed bill fields from an email.
 type Extracted struct {
-	TotalAmount float64
-	DueDate     time.Time
-	BillerCompany string
+	TotalAmount    money.Money `json:"totalAmount"`
+	DueDate        time.Time   `json:"dueDate"`
+	BillerCompany  string      `json:"billerCompany"`
+	AccountNumber  string      `json:"accountNumber,omitempty"` // as printed; may already be masked
+	ServiceAddress string      `json:"serviceAddress,omitempty"`
+	BillingPeriod  string      `json:"billingPeriod,omitempty"` // raw period text as it appears in the email
+	PeriodStart    time.Time   `json:"periodStart,omitempty"`   // first day of service, parsed from BillingPeriod
+	PeriodEnd      time.Time   `json:"periodEnd,omitempty"`     // last day of service
+	Usage          []Usage     `json:"usage,omitempty"`
+	LineItems      []LineItem  `json:"lineItems,omitempty"` // itemized charges, when the bill lists them
+
+	// Confidence is how likely TotalAmount is the amount due, from 0 to 1.
+	Confidence float64 `json:"confidence"`
+	// Evidence holds the text each field was read from.
+	Evidence []Evidence `json:"evidence"`
+	// Extractor is the name of the extractor that produced the result.
+	Extractor string `json:"extractor"`
+}
+
+// Extractor reads bill fields from a message. Extract returns false when the
+// extractor does not apply to the message or finds no total.
+type Extractor interface {
+	Name() string
+	Extract(src *Source) (*Extracted, bool)
 }
 
-// Extractor parses bill fields from email HTML/body. Per-biller logic can be added later.
-type Extractor struct {
+// defaultCurrency is assumed when neither the amount nor the profile names one.
+const defaultCurrency = "USD"
+
+// Scope selects which texts of a Source a RegexExtractor searches.
+type Scope int
+
+const (
+	// ScopeAll searches the body, then each readable attachment.
+	ScopeAll Scope = iota
+	// ScopeBody searches only the message body.
+	ScopeBody
+	// ScopeAttachments searches only readable attachments.
+	ScopeAttachments
+)
+
+// RegexExtractor parses bill fields with regular expressions.
+// When Profiles is set, only messages from a profiled biller are handled and
+// that biller's patterns take precedence over the defaults.
+type RegexExtractor struct {
+	name string
+
 	// TotalRegex is used to find the total amount in body (e.g. "Total: $123.45").
 	TotalRegex *regexp.Regexp
+	// DueDateRegex finds the due date in body (e.g. "Payment due: Oct 5, 2026").
+	DueDateRegex *regexp.Regexp
+	// AccountRegex finds the account number (e.g. "Account #: XXXX-4821").
+	AccountRegex *regexp.Regexp
+	// AddressRegex finds the service address (e.g. "Service address: 12 Main St").
+	AddressRegex *regexp.Regexp
+	// PeriodRegex finds the billing period (e.g. "Billing period: Aug 10 - Sep 9, 2026").
+	PeriodRegex *regexp.Regexp
+	// Location is the household time zone due dates are resolved in.
+	Location *time.Location
+	// Currency is assumed for amounts without a symbol or ISO code.
+	Currency string
+	// Profiles are per-biller patterns, chosen by the message sender.
+	Profiles []Profile
+	// Scope is which texts of the message are searched.
+	Scope Scope
+}
+
+// DefaultExtractor returns the generic fallback: default total and due date
+// patterns applied to the message body.
+func DefaultExtractor() *RegexExtractor {
+	return &RegexExtractor{
+		name:         "generic",
+		TotalRegex:   regexp.MustCompile(`(?i)(?:total|amount due|balance)[:\s]*` + amountPattern),
+		DueDateRegex: defaultDueDateRegex,
+		AccountRegex: defaultAccountRegex,
+		AddressRegex: defaultAddressRegex,
+		PeriodRegex:  defaultPeriodRegex,
+		Location:     time.UTC,
+		Currency:     defaultCurrency,
+		Scope:        ScopeBody,
+	}
 }
 
-// DefaultExtractor returns an extractor with a default total pattern.
-func DefaultExtractor() *Extractor {
-	return &Extractor{
-		TotalRegex: regexp.MustCompile(`(?i)(?:total|amount due|balance)[:\s]*\$?\s*([\d,]+(?:\.\d{2})?)`),
+// NewBillerExtractor returns an extractor that applies the per-biller profiles
+// to the body and attachments, resolving due dates in loc.
+func NewBillerExtractor(billers []config.BillerProfile, loc *time.Location) (*RegexExtractor, error) {
+	profiles, err := CompileProfiles(billers)
+	if err != nil {
+		return nil, err
+	}
+	e := DefaultExtractor()
+	e.name = "biller"
+	e.Profiles = profiles
+	e.Scope = ScopeAll
+	if loc != nil {
+		e.Location = loc
 	}
+	return e, nil
 }
 
-// Extract runs the extractor on the message and returns bill fields if found.
-func (e *Extractor) Extract(msg *gmail.Message, html, plain string) (*Extracted, bool) {
-	body := html
-	if body == "" {
-		body = plain
+// NewAttachmentExtractor returns an extractor that applies the default
+// patterns to the text of readable attachments such as PDFs.
+func NewAttachmentExtractor(loc *time.Location) *RegexExtractor {
+	e := DefaultExtractor()
+	e.name = "attachment"
+	e.Scope = ScopeAttachments
+	if loc != nil {
+		e.Location = loc
 	}
-	if body == "" {
+	return e
+}
+
+// Name implements Extractor.
+func (e *RegexExtractor) Name() string { return e.name }
+
+// Extract runs the extractor on the texts in its scope and returns bill fields
+// if found. Each field is taken from the first text that has it.
+func (e *RegexExtractor) Extract(src *Source) (*Extracted, bool) {
+	texts := src.texts(e.Scope)
+	if len(texts) == 0 {
 		return nil, false
 	}
 
-	out := &Extracted{}
-	out.BillerCompany = getHeader(msg, "From")
+	from := getHeader(src.Message, "From")
+	out := &Extracted{Extractor: e.name}
+	out.BillerCompany = SenderName(from)
 
-	if e.TotalRegex != nil {
-		matches := e.TotalRegex.FindStringSubmatch(body)
-		if len(matches) < 2 {
-			return nil, false
-		}
-		var total float64
-		if err := parseDecimal(matches[1], &total); err != nil {
-			return nil, false
+	profile := e.profileFor(from, src.Biller)
+	if e.Profiles != nil && profile == nil {
+		return nil, false
+	}
+	if profile != nil && profile.Name != "" {
+		out.BillerCompany = profile.Name
+	}
+	if src.Biller != "" {
+		out.BillerCompany = src.Biller
+	}
+
+	totalRegex := e.TotalRegex
+	fromProfile := profile != nil && profile.Total != nil
+	if fromProfile {
+		totalRegex = profile.Total
+	}
+	if totalRegex == nil {
+		return nil, false
+	}
+	h, ok := find(totalRegex, texts)
+	if !ok {
+		return nil, false
+	}
+	currency := e.Currency
+	if profile != nil && profile.Currency != "" {
+		currency = profile.Currency
+	}
+	total, err := parseAmount(h.Value, h.Match, currency)
+	if err != nil {
+		return nil, false
+	}
+	out.TotalAmount = total
+	out.Confidence = totalConfidence(h, fromProfile, countDistinct(totalRegex, texts))
+	out.Evidence = append(out.Evidence, h.evidence("total"))
+
+	dueRegex := e.DueDateRegex
+	if profile != nil && profile.DueDate != nil {
+		dueRegex = profile.DueDate
+	}
+	if h, ok := find(dueRegex, texts); ok {
+		if t, err := ParseDate(h.Value, e.Location); err == nil {
+			out.DueDate = t
+			out.Evidence = append(out.Evidence, h.evidence("dueDate"))
 		}
-		out.TotalAmount = total
 	}
 
-	// Due date: optional; can be extended with per-biller rules
-	// out.DueDate left as zero value
+	accountRegex := e.AccountRegex
+	if profile != nil && profile.Account != nil {
+		accountRegex = profile.Account
+	}
+	if h, ok := find(accountRegex, texts); ok {
+		out.AccountNumber = h.Value
+		out.Evidence = append(out.Evidence, h.evidence("account"))
+	}
+	addressRegex := e.AddressRegex
+	if profile != nil && profile.Address != nil {
+		addressRegex = profile.Address
+	}
+	if h, ok := find(addressRegex, texts); ok {
+		out.ServiceAddress = h.Value
+		out.Evidence = append(out.Evidence, h.evidence("address"))
+	}
+
+	periodRegex := e.PeriodRegex
+	if profile != nil && profile.Period != nil {
+		periodRegex = profile.Period
+	}
+	if h, ok := find(periodRegex, texts); ok {
+		out.BillingPeriod = h.Value
+		out.PeriodStart, out.PeriodEnd, _ = ParsePeriod(h.Value, e.Location)
+		out.Evidence = append(out.Evidence, h.evidence("period"))
+	}
+
+	if profile != nil && profile.LineItem != nil {
+		out.LineItems = findLineItems(profile.LineItem, texts, total.Currency)
+	}
+
+	usage, evidence := findUsage(texts)
+	out.Usage = usage
+	out.Evidence = append(out.Evidence, evidence...)
 
 	return out, true
 }
 
+// profileFor returns the first profile named after the directory biller or
+// whose senders match from, or nil.
+func (e *RegexExtractor) profileFor(from, biller string) *Profile {
+	for i := range e.Profiles {
+		if biller != "" && strings.EqualFold(e.Profiles[i].Name, biller) {
+			return &e.Profiles[i]
+		}
+	}
+	for i := range e.Profiles {
+		if e.Profiles[i].matches(from) {
+			return &e.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// SenderName returns a readable name for a From header: the display name if
+// there is one, otherwise the address.
+func SenderName(from string) string {
+	a, err := mail.ParseAddress(from)
+	if err != nil {
+		return strings.TrimSpace(from)
+	}
+	if a.Name != "" {
+		return a.Name
+	}
+	return a.Address
+}
+
 func getHeader(msg *gmail.Message, name string) string {
-	if msg.Payload == nil {
+	if msg == nil || msg.Payload == nil {
 		return ""
 	}
 	for _, h := range msg.Payload.Headers {
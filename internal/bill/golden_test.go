@@ -0,0 +1,151 @@
+package bill_test
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/akksell/rbn/internal/bill"
+	"github.com/akksell/rbn/internal/config"
+	"github.com/akksell/rbn/internal/gmail"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files from current extraction results")
+
+// golden is the stable, comparable form of an extraction result.
+type golden struct {
+	ForwardedBy string          `json:"forwardedBy,omitempty"`
+	Kind        bill.Kind       `json:"kind"`
+	Payment     *bill.Payment   `json:"payment,omitempty"` // payment confirmations only
+	Found       bool            `json:"found"`
+	Extractor   string          `json:"extractor,omitempty"`
+	Biller      string          `json:"biller,omitempty"`
+	Total       string          `json:"total,omitempty"`
+	Currency    string          `json:"currency,omitempty"`
+	DueDate     string          `json:"dueDate,omitempty"`
+	Account     string          `json:"account,omitempty"`
+	Address     string          `json:"address,omitempty"`
+	Period      string          `json:"period,omitempty"`
+	PeriodDays  string          `json:"periodDays,omitempty"` // parsed start/end, e.g. 2026-08-10/2026-09-09
+	Usage       []bill.Usage    `json:"usage,omitempty"`
+	LineItems   []lineItem      `json:"lineItems,omitempty"`
+	Confidence  float64         `json:"confidence,omitempty"`
+	Evidence    []bill.Evidence `json:"evidence,omitempty"`
+}
+
+type lineItem struct {
+	Description string `json:"description"`
+	Amount      string `json:"amount"`
+}
+
+// TestGoldenCorpus runs the extractor chain over every message in
+// testdata/golden/<biller>/ and compares the result with <name>.golden.json.
+// Run with -update after an intended change to regenerate the golden files.
+func TestGoldenCorpus(t *testing.T) {
+	root := filepath.Join("testdata", "golden")
+	cfg, err := config.LoadFile(filepath.Join(root, "config.yaml"))
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+	registry, err := bill.NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("registry: %v", err)
+	}
+	chain := registry.Chain(cfg.Review.MinConfidence)
+
+	paths, err := filepath.Glob(filepath.Join(root, "*", "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonPaths, _ := filepath.Glob(filepath.Join(root, "*", "*.json"))
+	for _, p := range jsonPaths {
+		if !strings.HasSuffix(p, ".golden.json") {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 {
+		t.Fatal("no messages in golden corpus")
+	}
+
+	for _, path := range paths {
+		name, _ := filepath.Rel(root, path)
+		t.Run(name, func(t *testing.T) {
+			msg, err := gmail.ReadMessageFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fwd, forwarded := gmail.Unforward(msg, nil)
+			if forwarded {
+				msg = fwd.Original
+			}
+			content := gmail.GetMessageContent(msg)
+			src := &bill.Source{Message: msg, HTML: content.HTML, Plain: content.Plain}
+			for _, a := range content.Attachments {
+				if a.Data != nil && bill.CanParse(a.MimeType, a.Filename) {
+					src.Attachments = append(src.Attachments, bill.Attachment{Filename: a.Filename, MimeType: a.MimeType, Data: a.Data})
+				}
+			}
+
+			got := toGolden(chain.Extract(src))
+			got.Kind = bill.Classify(src)
+			if forwarded {
+				got.ForwardedBy = fwd.By
+			}
+			if got.Kind == bill.KindPayment {
+				got.Payment, _ = bill.ExtractPayment(src, cfg.Location)
+			}
+			gotJSON, err := json.MarshalIndent(got, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotJSON = append(gotJSON, '\n')
+
+			goldenPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".golden.json"
+			if *update {
+				if err := os.WriteFile(goldenPath, gotJSON, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if string(want) != string(gotJSON) {
+				t.Errorf("extraction changed for %s\n--- want\n%s--- got\n%s", name, want, gotJSON)
+			}
+		})
+	}
+}
+
+func toGolden(x *bill.Extracted, ok bool) golden {
+	if !ok {
+		return golden{}
+	}
+	g := golden{
+		Found:      true,
+		Extractor:  x.Extractor,
+		Biller:     x.BillerCompany,
+		Total:      x.TotalAmount.String(),
+		Currency:   x.TotalAmount.Currency,
+		Account:    x.AccountNumber,
+		Address:    x.ServiceAddress,
+		Period:     x.BillingPeriod,
+		Usage:      x.Usage,
+		Confidence: x.Confidence,
+		Evidence:   x.Evidence,
+	}
+	if !x.DueDate.IsZero() {
+		g.DueDate = x.DueDate.Format("2006-01-02")
+	}
+	for _, li := range x.LineItems {
+		g.LineItems = append(g.LineItems, lineItem{Description: li.Description, Amount: li.Amount.String() + " " + li.Amount.Currency})
+	}
+	if !x.PeriodStart.IsZero() {
+		g.PeriodDays = x.PeriodStart.Format("2006-01-02") + "/" + x.PeriodEnd.Format("2006-01-02")
+	}
+	return g
+}
@@ -0,0 +1,86 @@
+package bill
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLToText renders an HTML email as plain text for extraction and excerpts.
+// Entities are decoded, non-breaking spaces become spaces, inline elements
+// (span, b, a, ...) are joined without extra spacing, each table row becomes
+// one line with its cells separated by tabs, and block elements start new lines.
+func HTMLToText(s string) string {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+	var b strings.Builder
+	renderText(&b, doc)
+	return cleanText(b.String())
+}
+
+func renderText(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(n.Data)
+		return
+	case html.CommentNode, html.DoctypeNode:
+		return
+	case html.ElementNode:
+		switch n.DataAtom {
+		case atom.Script, atom.Style, atom.Head, atom.Title, atom.Noscript:
+			return
+		case atom.Br:
+			b.WriteByte('\n')
+			return
+		case atom.Td, atom.Th:
+			b.WriteByte('\t')
+		case atom.Tr:
+			b.WriteByte('\n')
+		default:
+			if isBlock(n.DataAtom) {
+				b.WriteByte('\n')
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderText(b, c)
+	}
+	if n.Type == html.ElementNode && (n.DataAtom == atom.Tr || isBlock(n.DataAtom)) {
+		b.WriteByte('\n')
+	}
+}
+
+func isBlock(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Table, atom.Tbody, atom.Thead, atom.Tfoot,
+		atom.Ul, atom.Ol, atom.Li, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Section, atom.Article, atom.Header, atom.Footer, atom.Blockquote, atom.Pre,
+		atom.Hr, atom.Center, atom.Dl, atom.Dt, atom.Dd, atom.Address:
+		return true
+	}
+	return false
+}
+
+// cleanText collapses runs of spaces within each line, trims cell and line
+// padding and drops blank lines.
+func cleanText(s string) string {
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		var cells []string
+		for _, cell := range strings.Split(line, "\t") {
+			cell = strings.Join(strings.Fields(cell), " ")
+			if cell != "" {
+				cells = append(cells, cell)
+			}
+		}
+		if len(cells) > 0 {
+			lines = append(lines, strings.Join(cells, "\t"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
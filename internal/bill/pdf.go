@@ -0,0 +1,66 @@
+package bill
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// PDFText extracts the text of a PDF, one line per row of text on the page.
+func PDFText(data []byte) (text string, err error) {
+	// The PDF reader panics on some malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			text, err = "", fmt.Errorf("pdf: %v", r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("pdf: %w", err)
+	}
+
+	var b strings.Builder
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		writePDFRows(&b, page.Content().Text)
+	}
+	return b.String(), nil
+}
+
+// writePDFRows groups positioned glyphs into rows by baseline, orders each row
+// left to right and inserts a space wherever there is a visible gap.
+func writePDFRows(b *strings.Builder, glyphs []pdf.Text) {
+	rows := make(map[int][]pdf.Text)
+	for _, g := range glyphs {
+		y := int(math.Round(g.Y))
+		rows[y] = append(rows[y], g)
+	}
+	ys := make([]int, 0, len(rows))
+	for y := range rows {
+		ys = append(ys, y)
+	}
+	// PDF y grows upward, so the top of the page comes first.
+	sort.Sort(sort.Reverse(sort.IntSlice(ys)))
+
+	for _, y := range ys {
+		row := rows[y]
+		sort.SliceStable(row, func(i, j int) bool { return row[i].X < row[j].X })
+		end := math.Inf(-1)
+		for _, g := range row {
+			if end != math.Inf(-1) && g.X-end > g.FontSize*0.2 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(g.S)
+			end = g.X + g.W
+		}
+		b.WriteByte('\n')
+	}
+}
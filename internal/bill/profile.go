@@ -0,0 +1,87 @@
+package bill
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/akksell/rbn/internal/config"
+)
+
+// Profile holds the compiled extraction patterns for one biller.
+// A nil pattern means the extractor's default is used for that field.
+type Profile struct {
+	Name     string
+	Senders  []string
+	Currency string // assumed when the amount has no symbol or code
+
+	Total   *regexp.Regexp
+	DueDate *regexp.Regexp
+	Account *regexp.Regexp
+	Period  *regexp.Regexp
+	Address *regexp.Regexp
+	// LineItem captures a description (group 1) and amount (group 2) per match.
+	LineItem *regexp.Regexp
+
+	CSV         *config.CSVLayout // nil if the biller sends no CSV statement
+	CSVFilename *regexp.Regexp
+}
+
+// CompileProfiles compiles the biller profiles from config.
+func CompileProfiles(specs []config.BillerProfile) ([]Profile, error) {
+	out := make([]Profile, 0, len(specs))
+	for _, spec := range specs {
+		p := Profile{Name: spec.Name, Senders: spec.Senders, Currency: strings.ToUpper(spec.Currency)}
+		var err error
+		if p.Total, err = compileOptional(spec.TotalPattern); err != nil {
+			return nil, fmt.Errorf("biller %q total: %w", spec.Name, err)
+		}
+		if p.DueDate, err = compileOptional(spec.DueDatePattern); err != nil {
+			return nil, fmt.Errorf("biller %q due date: %w", spec.Name, err)
+		}
+		if p.Account, err = compileOptional(spec.AccountPattern); err != nil {
+			return nil, fmt.Errorf("biller %q account: %w", spec.Name, err)
+		}
+		if p.Period, err = compileOptional(spec.PeriodPattern); err != nil {
+			return nil, fmt.Errorf("biller %q period: %w", spec.Name, err)
+		}
+		if p.Address, err = compileOptional(spec.AddressPattern); err != nil {
+			return nil, fmt.Errorf("biller %q address: %w", spec.Name, err)
+		}
+		if p.LineItem, err = compileOptional(spec.LineItemPattern); err != nil {
+			return nil, fmt.Errorf("biller %q line item: %w", spec.Name, err)
+		}
+		if p.LineItem != nil && p.LineItem.NumSubexp() < 2 {
+			return nil, fmt.Errorf("biller %q line item: pattern needs description and amount groups", spec.Name)
+		}
+		if spec.CSV != nil {
+			if err := checkCSVLayout(spec.CSV); err != nil {
+				return nil, fmt.Errorf("biller %q csv: %w", spec.Name, err)
+			}
+			p.CSV = spec.CSV
+			if p.CSVFilename, err = compileOptional(spec.CSV.Filename); err != nil {
+				return nil, fmt.Errorf("biller %q csv filename: %w", spec.Name, err)
+			}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// matches reports whether the From header belongs to this biller.
+func (p *Profile) matches(from string) bool {
+	from = strings.ToLower(from)
+	for _, s := range p.Senders {
+		if s != "" && strings.Contains(from, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileOptional(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
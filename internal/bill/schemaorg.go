@@ -0,0 +1,313 @@
+package bill
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// schemaOrgConfidence is the confidence of a total read from Invoice markup.
+const schemaOrgConfidence = 0.95
+
+// SchemaOrgExtractor reads schema.org Invoice markup (JSON-LD or microdata)
+// from HTML bodies: totalPaymentDue, paymentDueDate, provider, accountId and
+// billingPeriod. Messages without an Invoice that states a total are skipped.
+type SchemaOrgExtractor struct {
+	// Location is the household time zone due dates are resolved in.
+	Location *time.Location
+	// Currency is assumed when the markup gives no priceCurrency.
+	Currency string
+}
+
+// NewSchemaOrgExtractor returns a schema.org extractor resolving dates in loc.
+func NewSchemaOrgExtractor(loc *time.Location) *SchemaOrgExtractor {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &SchemaOrgExtractor{Location: loc, Currency: defaultCurrency}
+}
+
+// Name implements Extractor.
+func (e *SchemaOrgExtractor) Name() string { return "schema.org" }
+
+// Extract implements Extractor.
+func (e *SchemaOrgExtractor) Extract(src *Source) (*Extracted, bool) {
+	if src.HTML == "" {
+		return nil, false
+	}
+	doc, err := html.Parse(strings.NewReader(src.HTML))
+	if err != nil {
+		return nil, false
+	}
+	for _, inv := range findInvoices(doc) {
+		if out, ok := e.fromInvoice(inv, src); ok {
+			return out, true
+		}
+	}
+	return nil, false
+}
+
+// fromInvoice maps one Invoice item to Extracted.
+func (e *SchemaOrgExtractor) fromInvoice(inv map[string]interface{}, src *Source) (*Extracted, bool) {
+	raw, ok := inv["totalPaymentDue"]
+	if !ok {
+		return nil, false
+	}
+	amount, currency := amountOf(raw)
+	if currency == "" {
+		currency = e.Currency
+	}
+	total, err := parseAmount(amount, amount, strings.ToUpper(currency))
+	if err != nil {
+		return nil, false
+	}
+
+	out := &Extracted{
+		Extractor:     e.Name(),
+		TotalAmount:   total,
+		BillerCompany: SenderName(getHeader(src.Message, "From")),
+		Confidence:    schemaOrgConfidence,
+	}
+	out.Evidence = append(out.Evidence, markupEvidence("total", "totalPaymentDue", strings.TrimSpace(amount+" "+currency)))
+
+	if name := nameOf(inv["provider"]); name != "" {
+		out.BillerCompany = name
+		out.Evidence = append(out.Evidence, markupEvidence("biller", "provider", name))
+	}
+	if src.Biller != "" {
+		out.BillerCompany = src.Biller
+	}
+	for _, prop := range []string{"paymentDueDate", "paymentDue"} {
+		s := stringOf(inv[prop])
+		if s == "" {
+			continue
+		}
+		if t, err := parseMarkupDate(s, e.Location); err == nil {
+			out.DueDate = t
+			out.Evidence = append(out.Evidence, markupEvidence("dueDate", prop, s))
+			break
+		}
+	}
+	if s := stringOf(inv["accountId"]); s != "" {
+		out.AccountNumber = s
+		out.Evidence = append(out.Evidence, markupEvidence("account", "accountId", s))
+	}
+	if s := stringOf(inv["billingPeriod"]); s != "" {
+		// billingPeriod is an ISO 8601 interval ("2026-08-10/2026-09-09")
+		// or a bare duration ("P1M"), which says nothing about the dates.
+		if from, to, found := strings.Cut(s, "/"); found {
+			start, err1 := parseMarkupDate(from, e.Location)
+			end, err2 := parseMarkupDate(to, e.Location)
+			if err1 == nil && err2 == nil {
+				out.BillingPeriod = s
+				out.PeriodStart, out.PeriodEnd = start, end
+				out.Evidence = append(out.Evidence, markupEvidence("period", "billingPeriod", s))
+			}
+		}
+	}
+	return out, true
+}
+
+func markupEvidence(field, prop, value string) Evidence {
+	return Evidence{Field: field, Text: prop + ": " + value, Source: "schema.org"}
+}
+
+// parseMarkupDate reads an ISO 8601 date or date-time, keeping the calendar date.
+func parseMarkupDate(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 10 && s[10] == 'T' {
+		s = s[:10]
+	}
+	return ParseDate(s, loc)
+}
+
+// findInvoices returns the Invoice items in JSON-LD scripts and microdata, in
+// document order. Microdata items use the same property map shape as JSON-LD.
+func findInvoices(n *html.Node) []map[string]interface{} {
+	var out []map[string]interface{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.DataAtom == atom.Script && strings.EqualFold(attr(n, "type"), "application/ld+json") && n.FirstChild != nil {
+				var v interface{}
+				if json.Unmarshal([]byte(n.FirstChild.Data), &v) == nil {
+					collectInvoices(v, &out)
+				}
+				return
+			}
+			if hasAttr(n, "itemscope") && isInvoiceType(attr(n, "itemtype")) {
+				out = append(out, microdataItem(n))
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return out
+}
+
+// collectInvoices appends every object typed Invoice within a JSON-LD value,
+// including those in @graph and nested properties.
+func collectInvoices(v interface{}, out *[]map[string]interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if isInvoiceType(v["@type"]) {
+			*out = append(*out, v)
+		}
+		for _, child := range v {
+			collectInvoices(child, out)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectInvoices(child, out)
+		}
+	}
+}
+
+func isInvoiceType(t interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		for _, name := range strings.Fields(t) {
+			name = name[strings.LastIndexAny(name, "/:")+1:]
+			if name == "Invoice" {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, x := range t {
+			if isInvoiceType(x) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// microdataItem collects the itemprop values under an itemscope element.
+// Nested itemscopes become nested maps and keep their own properties.
+func microdataItem(n *html.Node) map[string]interface{} {
+	item := map[string]interface{}{"@type": attr(n, "itemtype")}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			prop := attr(c, "itemprop")
+			nested := hasAttr(c, "itemscope")
+			if prop != "" {
+				var v interface{}
+				if nested {
+					v = microdataItem(c)
+				} else {
+					v = microdataValue(c)
+				}
+				for _, name := range strings.Fields(prop) {
+					if _, seen := item[name]; !seen {
+						item[name] = v
+					}
+				}
+			}
+			if !nested {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return item
+}
+
+func microdataValue(n *html.Node) string {
+	if v, ok := attrOK(n, "content"); ok {
+		return v
+	}
+	switch n.DataAtom {
+	case atom.Time:
+		if v, ok := attrOK(n, "datetime"); ok {
+			return v
+		}
+	case atom.Data, atom.Meter:
+		if v, ok := attrOK(n, "value"); ok {
+			return v
+		}
+	case atom.A, atom.Link:
+		return attr(n, "href")
+	}
+	var b strings.Builder
+	renderText(&b, n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// amountOf returns the amount and currency of a price: a MonetaryAmount or
+// PriceSpecification object, or a bare number or string.
+func amountOf(v interface{}) (amount, currency string) {
+	if m, ok := v.(map[string]interface{}); ok {
+		for _, k := range []string{"price", "value", "amount"} {
+			if s := stringOf(m[k]); s != "" {
+				amount = s
+				break
+			}
+		}
+		for _, k := range []string{"priceCurrency", "currency"} {
+			if s := stringOf(m[k]); s != "" {
+				currency = s
+				break
+			}
+		}
+		return amount, currency
+	}
+	return stringOf(v), ""
+}
+
+// nameOf returns the name of an Organization or Person, or a bare string.
+func nameOf(v interface{}) string {
+	if m, ok := v.(map[string]interface{}); ok {
+		return stringOf(m["name"])
+	}
+	return stringOf(v)
+}
+
+func stringOf(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}:
+		if s, ok := v["@value"]; ok {
+			return stringOf(s)
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			return stringOf(v[0])
+		}
+	case nil:
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
+
+func attr(n *html.Node, key string) string {
+	v, _ := attrOK(n, key)
+	return v
+}
+
+func attrOK(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	_, ok := attrOK(n, key)
+	return ok
+}
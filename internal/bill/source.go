@@ -0,0 +1,82 @@
+package bill
+
+import (
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// Source is everything the extractor can read from one message.
+type Source struct {
+	Message     *gmail.Message
+	HTML        string
+	Plain       string
+	Attachments []Attachment
+	// Biller is the canonical biller name from the biller directory, if the
+	// sender is listed there. It becomes Extracted.BillerCompany.
+	Biller string
+
+	// attachmentTexts caches parsed attachment text across extractors.
+	attachmentTexts []sourceText
+	parsed          bool
+}
+
+// Attachment is a downloaded message attachment.
+type Attachment struct {
+	Filename string
+	MimeType string
+	Data     []byte
+}
+
+// CanParse reports whether the extractor reads attachments of this type,
+// so callers only download the ones that matter.
+func CanParse(mimeType, filename string) bool {
+	return isPDF(mimeType, filename) || isXML(mimeType, filename) || isCSV(mimeType, filename)
+}
+
+func isPDF(mimeType, filename string) bool {
+	return strings.EqualFold(mimeType, "application/pdf") ||
+		strings.HasSuffix(strings.ToLower(filename), ".pdf")
+}
+
+// BodyText returns the message body as normalized plain text, preferring
+// the HTML body since billers tend to put the full statement there.
+func (src *Source) BodyText() string {
+	if src.HTML != "" {
+		return HTMLToText(src.HTML)
+	}
+	return cleanText(src.Plain)
+}
+
+// texts returns the searchable texts of the source within scope, in priority
+// order: the message body first, then the text of each readable attachment.
+func (src *Source) texts(scope Scope) []sourceText {
+	var out []sourceText
+	if scope != ScopeAttachments {
+		if body := src.BodyText(); body != "" {
+			out = append(out, sourceText{Name: "body", Text: body})
+		}
+	}
+	if scope != ScopeBody {
+		out = append(out, src.readAttachments()...)
+	}
+	return out
+}
+
+func (src *Source) readAttachments() []sourceText {
+	if src.parsed {
+		return src.attachmentTexts
+	}
+	src.parsed = true
+	for _, a := range src.Attachments {
+		if !isPDF(a.MimeType, a.Filename) {
+			continue
+		}
+		text, err := PDFText(a.Data)
+		if err != nil || strings.TrimSpace(text) == "" {
+			continue
+		}
+		src.attachmentTexts = append(src.attachmentTexts, sourceText{Name: a.Filename, Text: text})
+	}
+	return src.attachmentTexts
+}
@@ -0,0 +1,118 @@
+package bill
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// defaultPeriodRegex captures a date range introduced by "billing period",
+	// "service period", "service from" and similar.
+	defaultPeriodRegex = regexp.MustCompile(`(?i)(?:billing|service|statement)\s+(?:period|dates?|from)[:\s]*(?:from\s+)?(` +
+		periodDate + `\s*(?:-|–|to|through|thru)\s*` + periodDate + `)`)
+
+	// ³ is not a word character, so \b would never follow "m³"; only the
+	// ASCII units need the boundary.
+	usageRegex = regexp.MustCompile(`(?i)(\d{1,3}(?:,\d{3})*(?:\.\d+)?|\d+(?:\.\d+)?)\s*` +
+		`((?:kwh|therms?|ccf|hcf|gallons?|gal|gb|tb|cubic meters|m3)\b|m³)`)
+	usageWord = regexp.MustCompile(`(?i)\b(?:used|usage|consum(?:ed|ption)|metered|total)\b`)
+
+	// usageUnits normalizes unit spellings.
+	usageUnits = map[string]string{
+		"kwh": "kWh", "therm": "therm", "therms": "therm", "ccf": "CCF", "hcf": "HCF",
+		"gallon": "gal", "gallons": "gal", "gal": "gal", "gb": "GB", "tb": "TB",
+		"cubic meters": "m³", "m3": "m³", "m³": "m³",
+	}
+)
+
+// periodDate is datePattern without the capture group, also allowing a
+// month and day without a year ("Aug 10 - Sep 9, 2026").
+const periodDate = `(?:\d{4}-\d{1,2}-\d{1,2}` +
+	`|\d{1,2}[/-]\d{1,2}[/-](?:\d{4}|\d{2})` +
+	`|[A-Za-z]{3,9}\.?\s+\d{1,2}(?:st|nd|rd|th)?(?:,?\s+\d{4})?` +
+	`|\d{1,2}(?:st|nd|rd|th)?\s+[A-Za-z]{3,9}\.?(?:,?\s+\d{4})?)`
+
+// Usage is a metered quantity printed on a bill, e.g. 412 kWh.
+type Usage struct {
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"` // kWh, therm, CCF, HCF, gal, GB, TB or m³
+}
+
+// ParsePeriod splits billing period text such as "Aug 10, 2026 - Sep 9, 2026"
+// into its first and last day. A start date without a year takes the end
+// date's year, or the year before if that would put it after the end.
+func ParsePeriod(s string, loc *time.Location) (start, end time.Time, ok bool) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+	for _, sep := range periodSeparators {
+		for i := 0; ; {
+			j := strings.Index(lower[i:], sep)
+			if j < 0 {
+				break
+			}
+			j += i
+			if start, end, ok := parseRange(s[:j], s[j+len(sep):], loc); ok {
+				return start, end, true
+			}
+			i = j + len(sep)
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// periodSeparators are tried in order; spaced forms first so the hyphens
+// inside ISO dates aren't taken for the range separator.
+var periodSeparators = []string{" - ", " – ", " to ", " through ", " thru ", "–", "-"}
+
+func parseRange(from, to string, loc *time.Location) (start, end time.Time, ok bool) {
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	end, err := ParseDate(to, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	start, err = ParseDate(from, loc)
+	if err != nil {
+		start, err = ParseDate(from+", "+strconv.Itoa(end.Year()), loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		if start.After(end) {
+			start = start.AddDate(-1, 0, 0)
+		}
+	}
+	if start.After(end) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// findUsage returns the first quantity per unit on lines that talk about
+// usage, with the evidence for each.
+func findUsage(texts []sourceText) ([]Usage, []Evidence) {
+	var usage []Usage
+	var evidence []Evidence
+	seen := make(map[string]bool)
+	for _, t := range texts {
+		for _, line := range strings.Split(t.Text, "\n") {
+			if !usageWord.MatchString(line) {
+				continue
+			}
+			for _, m := range usageRegex.FindAllStringSubmatch(line, -1) {
+				unit := usageUnits[strings.ToLower(m[2])]
+				if seen[unit] {
+					continue
+				}
+				q, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64)
+				if err != nil {
+					continue
+				}
+				seen[unit] = true
+				usage = append(usage, Usage{Quantity: q, Unit: unit})
+				evidence = append(evidence, Evidence{Field: "usage", Text: strings.TrimSpace(line), Source: t.Name})
+			}
+		}
+	}
+	return usage, evidence
+}
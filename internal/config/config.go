@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	secretmanagerpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
@@ -16,11 +17,17 @@ import (
 // The server uses Application Default Credentials (e.g. the service account
 // attached to the Cloud Run service); no credential path is configured here.
 type Config struct {
-	Port               string     // env: PORT
-	FirestoreProjectID string     // env: FIRESTORE_PROJECT_ID
-	GmailTopicName     string     // env: GMAIL_TOPIC_NAME
-	GmailInboxUser     string     // Secret Manager: gmail-inbox-user
-	Filters            FilterSpec // GCS: gs://$CONFIG_BUCKET/config.yaml
+	Port               string          // env: PORT
+	FirestoreProjectID string          // env: FIRESTORE_PROJECT_ID
+	GmailTopicName     string          // env: GMAIL_TOPIC_NAME
+	GmailInboxUser     string          // Secret Manager: gmail-inbox-user
+	Filters            FilterSpec      // GCS: gs://$CONFIG_BUCKET/config.yaml
+	Billers            []BillerProfile // GCS: gs://$CONFIG_BUCKET/config.yaml
+	Location           *time.Location  // GCS: timezone (household time zone, default UTC)
+	Review             ReviewSpec      // GCS: gs://$CONFIG_BUCKET/config.yaml
+	Households         []HouseholdSpec // GCS: gs://$CONFIG_BUCKET/config.yaml
+	Split              SplitSpec       // GCS: gs://$CONFIG_BUCKET/config.yaml
+	Validation         ValidationSpec  // GCS: gs://$CONFIG_BUCKET/config.yaml
 }
 
 // FilterSpec defines which messages are treated as bills.
@@ -28,10 +35,118 @@ type FilterSpec struct {
 	BillerSenders []string `yaml:"billerSenders"`
 	Keywords      []string `yaml:"keywords"`
 	LabelIDs      []string `yaml:"labelIDs"`
+	// Forwarders lists addresses, besides active roommates, whose forwarded
+	// bills are unwrapped. Forwards from anyone else are processed as is,
+	// since an inline forward's From line is just body text.
+	Forwarders []string `yaml:"forwarders"`
+}
+
+// BillerProfile describes how to extract bill fields from one biller's emails.
+// Patterns are regular expressions whose first capture group holds the value;
+// empty patterns fall back to the default extractor's behavior.
+type BillerProfile struct {
+	Name           string   `yaml:"name"`
+	Senders        []string `yaml:"senders"`  // matched as substrings of the From header
+	Currency       string   `yaml:"currency"` // ISO code for amounts without a symbol; default USD
+	TotalPattern   string   `yaml:"totalPattern"`
+	DueDatePattern string   `yaml:"dueDatePattern"`
+	AccountPattern string   `yaml:"accountPattern"`
+	PeriodPattern  string   `yaml:"periodPattern"`
+	AddressPattern string   `yaml:"addressPattern"`
+	// LineItemPattern finds itemized charges: the first group is the
+	// description and the second the amount. Every match is a line item.
+	LineItemPattern string     `yaml:"lineItemPattern"`
+	CSV             *CSVLayout `yaml:"csv"` // layout of the biller's CSV statement attachment, if it sends one
+}
+
+// CSVLayout describes a CSV statement attachment. Columns are found by header
+// name, case-insensitively; the first row containing all named columns is the header.
+type CSVLayout struct {
+	Filename          string `yaml:"filename"`          // regexp matched against the attachment filename; default any .csv
+	Delimiter         string `yaml:"delimiter"`         // single character; default ","
+	DescriptionColumn string `yaml:"descriptionColumn"` // line item description
+	AmountColumn      string `yaml:"amountColumn"`      // line item amount
+	DueDateColumn     string `yaml:"dueDateColumn"`     // optional; the first non-empty value is the due date
+	AccountColumn     string `yaml:"accountColumn"`     // optional; the first non-empty value is the account number
+	TotalLabel        string `yaml:"totalLabel"`        // description of the row holding the total; default is the sum of line items
+}
+
+// HouseholdSpec routes bills to one household's roommates by the account
+// number or service address on the bill.
+type HouseholdSpec struct {
+	Name      string   `yaml:"name"`      // matched against roommates' household field
+	Accounts  []string `yaml:"accounts"`  // last digits of account numbers, e.g. "4821"
+	Addresses []string `yaml:"addresses"` // substrings of the service address, e.g. "Apt 2"
+}
+
+// SplitSpec controls how bills are divided among roommates.
+type SplitSpec struct {
+	Rules     []SplitRule    `yaml:"rules"`
+	LineItems []LineItemRule `yaml:"lineItems"`
+}
+
+// SplitRule limits which roommates share the bills of one biller or category
+// and how much each pays. The first matching rule wins; bills no rule matches
+// are shared by every roommate by their own weight.
+type SplitRule struct {
+	Biller    string               `yaml:"biller"`    // the bill's biller name or directory ID, case-insensitive
+	Category  string               `yaml:"category"`  // biller category, e.g. gas; used if biller is empty
+	Roommates []string             `yaml:"roommates"` // roommate IDs or emails taking part; empty means everyone
+	Exclude   []string             `yaml:"exclude"`   // roommate IDs or emails left out
+	Shares    map[string]ShareSpec `yaml:"shares"`    // by roommate ID or email; overrides the roommate's own share
+}
+
+// ShareSpec sizes one roommate's part of a bill, see store.Share.
+type ShareSpec struct {
+	Weight  float64 `yaml:"weight"`
+	Percent float64 `yaml:"percent"`
+	Fixed   float64 `yaml:"fixed"` // flat amount in major units, e.g. 50 for a parking spot
+	Cap     float64 `yaml:"cap"`   // most the roommate pays; 0 means no cap
+	Floor   float64 `yaml:"floor"` // least the roommate pays; 0 means no floor
+}
+
+// LineItemRule charges matching line items to some roommates only, e.g. premium
+// channels to the roommates who watch them. The first matching rule wins;
+// unmatched items and the unitemized rest of the bill are shared by everyone.
+type LineItemRule struct {
+	Biller      string   `yaml:"biller"`      // optional; the bill's biller name, case-insensitive
+	Description string   `yaml:"description"` // regexp matched against the line item description
+	Roommates   []string `yaml:"roommates"`   // roommate IDs or emails
+}
+
+// ValidationSpec holds the sanity checks a bill's amount must pass before it
+// is split. Bills that fail are held for review and the admin is alerted.
+// Non-positive amounts always fail.
+type ValidationSpec struct {
+	Limits       map[string]AmountLimits `yaml:"limits"`       // by ISO 4217 code, e.g. USD; bills in other currencies have no limits
+	MaxDeviation float64                 `yaml:"maxDeviation"` // allowed fraction above or below the biller's average, e.g. 0.5; 0 disables
+	HistoryBills int                     `yaml:"historyBills"` // most recent bills averaged; default 6
+	MinHistory   int                     `yaml:"minHistory"`   // bills needed before deviation is checked; default 3
+	AdminEmail   string                  `yaml:"adminEmail"`   // receives held-bill alerts; empty sends none
+}
+
+// AmountLimits bounds a bill's amount in major units of one currency; 0 means
+// no limit. They are never converted, so ¥5000 is not held by a USD limit.
+type AmountLimits struct {
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+}
+
+// ReviewSpec controls when extracted bills are held for manual review.
+type ReviewSpec struct {
+	// MinConfidence is the extraction confidence (0-1) below which a bill is
+	// saved as needs_review instead of being split and sent. 0 disables review.
+	MinConfidence float64 `yaml:"minConfidence"`
 }
 
 type controlPlaneConfig struct {
-	Filters FilterSpec `yaml:"filters"`
+	Filters    FilterSpec      `yaml:"filters"`
+	Billers    []BillerProfile `yaml:"billers"`
+	Timezone   string          `yaml:"timezone"` // IANA name, e.g. America/Los_Angeles
+	Review     ReviewSpec      `yaml:"review"`
+	Households []HouseholdSpec `yaml:"households"`
+	Split      SplitSpec       `yaml:"split"`
+	Validation ValidationSpec  `yaml:"validation"`
 }
 
 const (
@@ -62,17 +177,28 @@ func Load(ctx context.Context) (*Config, error) {
 		return nil, fmt.Errorf("gmail inbox user: %w", err)
 	}
 
-	filters, err := fetchGCSConfig(ctx, configBucket)
+	cp, err := fetchGCSConfig(ctx, configBucket)
 	if err != nil {
 		return nil, fmt.Errorf("GCS config: %w", err)
 	}
 
+	loc, err := loadLocation(cp.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("timezone: %w", err)
+	}
+
 	return &Config{
 		Port:               getEnv("PORT", "8080"),
 		FirestoreProjectID: projectID,
 		GmailTopicName:     gmailTopicName,
 		GmailInboxUser:     inboxUser,
-		Filters:            filters,
+		Filters:            cp.Filters,
+		Billers:            cp.Billers,
+		Location:           loc,
+		Review:             cp.Review,
+		Households:         cp.Households,
+		Split:              cp.Split,
+		Validation:         cp.Validation,
 	}, nil
 }
 
@@ -91,29 +217,37 @@ func fetchSecret(ctx context.Context, projectID, secretName string) (string, err
 	return string(result.Payload.Data), nil
 }
 
-func fetchGCSConfig(ctx context.Context, bucket string) (FilterSpec, error) {
+func fetchGCSConfig(ctx context.Context, bucket string) (controlPlaneConfig, error) {
 	client, err := storage.NewClient(ctx)
 	if err != nil {
-		return FilterSpec{}, fmt.Errorf("create client: %w", err)
+		return controlPlaneConfig{}, fmt.Errorf("create client: %w", err)
 	}
 	defer client.Close()
 
 	r, err := client.Bucket(bucket).Object(gcsConfigObject).NewReader(ctx)
 	if err != nil {
-		return FilterSpec{}, fmt.Errorf("open object: %w", err)
+		return controlPlaneConfig{}, fmt.Errorf("open object: %w", err)
 	}
 	defer r.Close()
 
 	data, err := io.ReadAll(r)
 	if err != nil {
-		return FilterSpec{}, fmt.Errorf("read: %w", err)
+		return controlPlaneConfig{}, fmt.Errorf("read: %w", err)
 	}
 
 	var cp controlPlaneConfig
 	if err := yaml.Unmarshal(data, &cp); err != nil {
-		return FilterSpec{}, fmt.Errorf("parse yaml: %w", err)
+		return controlPlaneConfig{}, fmt.Errorf("parse yaml: %w", err)
+	}
+	return cp, nil
+}
+
+// loadLocation resolves an IANA time zone name; empty means UTC.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
 	}
-	return cp.Filters, nil
+	return time.LoadLocation(name)
 }
 
 func getEnv(key, def string) string {
@@ -3,13 +3,30 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // File represents optional YAML config file structure.
 type File struct {
-	Filters *FilterSpec `yaml:"filters,omitempty"`
+	Filters    *FilterSpec     `yaml:"filters,omitempty"`
+	Billers    []BillerProfile `yaml:"billers,omitempty"`
+	Timezone   string          `yaml:"timezone,omitempty"`
+	Review     *ReviewSpec     `yaml:"review,omitempty"`
+	Households []HouseholdSpec `yaml:"households,omitempty"`
+	Split      *SplitSpec      `yaml:"split,omitempty"`
+	Validation *ValidationSpec `yaml:"validation,omitempty"`
+}
+
+// LoadFile reads a local YAML file with the same layout as the GCS config.
+// It is meant for offline tools: environment variables and secrets are not read.
+func LoadFile(path string) (*Config, error) {
+	c := &Config{Location: time.UTC}
+	if err := loadFile(path, c); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 func loadFile(path string, c *Config) error {
@@ -24,5 +41,27 @@ func loadFile(path string, c *Config) error {
 	if f.Filters != nil {
 		c.Filters = *f.Filters
 	}
+	if f.Billers != nil {
+		c.Billers = f.Billers
+	}
+	if f.Timezone != "" {
+		loc, err := loadLocation(f.Timezone)
+		if err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+		c.Location = loc
+	}
+	if f.Review != nil {
+		c.Review = *f.Review
+	}
+	if f.Households != nil {
+		c.Households = f.Households
+	}
+	if f.Split != nil {
+		c.Split = *f.Split
+	}
+	if f.Validation != nil {
+		c.Validation = *f.Validation
+	}
 	return nil
 }
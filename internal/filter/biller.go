@@ -0,0 +1,49 @@
+package filter
+
+import (
+	"net/mail"
+	"strings"
+
+	"github.com/akksell/rbn/internal/store"
+)
+
+// LookupBiller returns the directory entry for the sender in a From header,
+// or nil if there is none. Full address entries win over domain entries, and
+// a more specific domain ("billing.pge.com") wins over its parent ("pge.com").
+func LookupBiller(billers []store.Biller, from string) *store.Biller {
+	addr := strings.ToLower(senderAddress(from))
+	if addr == "" {
+		return nil
+	}
+	_, domain, _ := strings.Cut(addr, "@")
+
+	var best *store.Biller
+	bestScore := 0
+	for i := range billers {
+		for _, s := range billers[i].Senders {
+			s = strings.ToLower(strings.TrimSpace(s))
+			score := 0
+			switch {
+			case s == "":
+			case strings.Contains(s, "@"):
+				if s == addr {
+					score = 1000
+				}
+			case domain == s || strings.HasSuffix(domain, "."+s):
+				score = len(s)
+			}
+			if score > bestScore {
+				best, bestScore = &billers[i], score
+			}
+		}
+	}
+	return best
+}
+
+func senderAddress(from string) string {
+	a, err := mail.ParseAddress(from)
+	if err != nil {
+		return strings.Trim(strings.TrimSpace(from), "<>")
+	}
+	return a.Address
+}
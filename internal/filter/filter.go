@@ -69,3 +69,35 @@ func getHeader(msg *gmail.Message, name string) string {
 	}
 	return ""
 }
+
+// Household returns the name of the first household whose account numbers or
+// service addresses match the bill, or "" if none does. Accounts match on
+// trailing digits so masked numbers ("****4821") route the same as full ones.
+func Household(households []config.HouseholdSpec, account, address string) string {
+	digits := onlyDigits(account)
+	addr := strings.ToLower(address)
+	for _, h := range households {
+		for _, a := range h.Accounts {
+			want := onlyDigits(a)
+			if want != "" && digits != "" && strings.HasSuffix(digits, want) {
+				return h.Name
+			}
+		}
+		for _, a := range h.Addresses {
+			if a != "" && addr != "" && strings.Contains(addr, strings.ToLower(a)) {
+				return h.Name
+			}
+		}
+	}
+	return ""
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
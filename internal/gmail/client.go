@@ -4,9 +4,9 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"mime"
 	"net/mail"
 	"strconv"
-	"strings"
 
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/gmail/v1"
@@ -92,65 +92,32 @@ func (c *Client) GetMessage(ctx context.Context, messageID string) (*gmail.Messa
 // from is the From address (typically the same as the inbox user); to, subject, and body are plain text.
 func (c *Client) SendMessage(ctx context.Context, from, to, subject, body string) error {
 	raw := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
-		mail.FormatAddress(from, ""), to, mimeEncodeSubject(subject), body)
+		(&mail.Address{Address: from}).String(), to, mimeEncodeSubject(subject), body)
 	rawB64 := base64.RawURLEncoding.EncodeToString([]byte(raw))
 	msg := &gmail.Message{Raw: rawB64}
 	_, err := c.svc.Users.Messages.Send(c.userID, msg).Context(ctx).Do()
 	return err
 }
 
+// mimeEncodeSubject encodes s as an RFC 2047 encoded-word if it is not plain
+// ASCII, e.g. for currency symbols and non-ASCII biller names.
 func mimeEncodeSubject(s string) string {
-	// Simple ASCII subject; no need for MIME encoding for typical bill subjects
-	return s
+	return mime.QEncoding.Encode("utf-8", s)
 }
 
-// GetMessageBody returns the HTML or plain body of the message.
-func GetMessageBody(msg *gmail.Message) (html string, plain string) {
-	if msg.Payload == nil {
-		return "", ""
-	}
-	for _, p := range msg.Payload.Parts {
-		if p.MimeType == "text/html" && p.Body != nil && p.Body.AttachmentId == "" {
-			html = decodeBody(p.Body)
-			break
-		}
-	}
-	if html == "" && msg.Payload.Body != nil {
-		if msg.Payload.MimeType == "text/html" {
-			html = decodeBody(msg.Payload.Body)
-		} else {
-			plain = decodeBody(msg.Payload.Body)
-		}
-	}
-	for _, p := range msg.Payload.Parts {
-		if p.MimeType == "text/plain" && p.Body != nil && p.Body.AttachmentId == "" {
-			plain = decodeBody(p.Body)
-			break
-		}
+// GetAttachment downloads an attachment through the attachments API.
+func (c *Client) GetAttachment(ctx context.Context, messageID, attachmentID string) ([]byte, error) {
+	body, err := c.svc.Users.Messages.Attachments.Get(c.userID, messageID, attachmentID).Context(ctx).Do()
+	if err != nil {
+		return nil, err
 	}
-	return html, plain
+	return base64.URLEncoding.DecodeString(padBase64(body.Data))
 }
 
-func decodeBody(b *gmail.MessagePartBody) string {
-	if b == nil {
-		return ""
-	}
-	data := b.Data
-	if data == "" {
-		return ""
-	}
-	// Gmail API returns base64url
-	data = strings.ReplaceAll(data, "-", "+")
-	data = strings.ReplaceAll(data, "_", "/")
-	switch len(data) % 4 {
-	case 2:
-		data += "=="
-	case 3:
-		data += "="
-	}
-	decoded, err := base64.StdEncoding.DecodeString(data)
-	if err != nil {
-		return ""
+// AttachmentData returns the bytes of a, downloading them if they were not returned inline.
+func (c *Client) AttachmentData(ctx context.Context, messageID string, a Attachment) ([]byte, error) {
+	if a.Data != nil || a.AttachmentID == "" {
+		return a.Data, nil
 	}
-	return string(decoded)
+	return c.GetAttachment(ctx, messageID, a.AttachmentID)
 }
@@ -0,0 +1,225 @@
+package gmail
+
+import (
+	"encoding/base64"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/akksell/rbn/internal/bill"
+	"google.golang.org/api/gmail/v1"
+)
+
+// Forward is a bill that a roommate forwarded to the billing inbox.
+type Forward struct {
+	// By is the From header of the forwarding message.
+	By string
+	// Original is the forwarded message, rebuilt with its own From, Subject
+	// and Date headers, its body and the attachments that came with it.
+	// It keeps the outer message's ID, so attachments are still fetched
+	// through the outer message.
+	Original *gmail.Message
+}
+
+// forwardMarker matches the separator mail clients put above an inline forward.
+var forwardMarker = regexp.MustCompile(`(?im)^[ \t>]*(?:-{2,}\s*Forwarded message\s*-{2,}|Begin forwarded message:|-{2,}\s*Original Message\s*-{2,})[ \t]*$`)
+
+// forwardMarkerHTML finds the same separator in HTML source, where it shares
+// a line with markup.
+var forwardMarkerHTML = regexp.MustCompile(`(?i)-{2,}\s*Forwarded message\s*-{2,}|Begin forwarded message:|-{2,}\s*Original Message\s*-{2,}`)
+
+// forwardHeader matches one header line of an inline forward block.
+var forwardHeader = regexp.MustCompile(`(?i)^[ \t>]*(From|Sent|Date|Subject|To|Cc):[ \t]*(.*)$`)
+
+// Unforward detects a forwarded message: an attached message/rfc822 part or an
+// inline forward block in the body. fetch downloads a part whose data is not
+// inline; it may be nil for messages read from disk.
+func Unforward(msg *gmail.Message, fetch func(Attachment) ([]byte, error)) (*Forward, bool) {
+	if msg == nil || msg.Payload == nil {
+		return nil, false
+	}
+	by := Header(msg, "From")
+	if inner := findRFC822(msg.Payload, fetch); inner != nil {
+		return &Forward{By: by, Original: rewrap(msg, inner)}, true
+	}
+	if inner := inlineForward(msg); inner != nil {
+		return &Forward{By: by, Original: rewrap(msg, inner)}, true
+	}
+	return nil, false
+}
+
+// rewrap gives the original payload the outer message's identity.
+func rewrap(outer *gmail.Message, payload *gmail.MessagePart) *gmail.Message {
+	m := &gmail.Message{
+		Id:           outer.Id,
+		ThreadId:     outer.ThreadId,
+		LabelIds:     outer.LabelIds,
+		HistoryId:    outer.HistoryId,
+		InternalDate: outer.InternalDate,
+		Payload:      payload,
+	}
+	c := GetMessageContent(m)
+	m.Snippet = snippet(c)
+	return m
+}
+
+func snippet(c *Content) string {
+	text := c.Plain
+	if text == "" {
+		text = bill.HTMLToText(c.HTML)
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > 200 {
+		text = string(r[:200])
+	}
+	return text
+}
+
+// findRFC822 returns the payload of the first attached message, parsed if
+// it arrived as raw data.
+func findRFC822(p *gmail.MessagePart, fetch func(Attachment) ([]byte, error)) *gmail.MessagePart {
+	if strings.EqualFold(p.MimeType, "message/rfc822") {
+		if len(p.Parts) == 1 && partHeader(p.Parts[0], "From") != "" {
+			return p.Parts[0]
+		}
+		if len(p.Parts) > 0 {
+			return &gmail.MessagePart{MimeType: "multipart/mixed", Headers: p.Headers, Parts: p.Parts}
+		}
+		raw := partData(p)
+		if raw == nil && fetch != nil && p.Body != nil && p.Body.AttachmentId != "" {
+			raw, _ = fetch(Attachment{PartID: p.PartId, Filename: p.Filename, MimeType: p.MimeType, AttachmentID: p.Body.AttachmentId})
+		}
+		if raw == nil {
+			return nil
+		}
+		if inner, err := ParseRaw(raw); err == nil {
+			return inner.Payload
+		}
+		return nil
+	}
+	for _, child := range p.Parts {
+		if inner := findRFC822(child, fetch); inner != nil {
+			return inner
+		}
+	}
+	return nil
+}
+
+// inlineForward rebuilds the message quoted below a forward marker. The
+// headers come from the plain-text body (or the HTML rendered as text); the
+// new body is everything after the header block, and the outer attachments
+// are kept since forwarding clients carry the original's attachments along.
+func inlineForward(msg *gmail.Message) *gmail.MessagePart {
+	c := GetMessageContent(msg)
+	text := c.Plain
+	if text == "" || !forwardMarker.MatchString(text) {
+		text = bill.HTMLToText(c.HTML)
+	}
+	loc := forwardMarker.FindStringIndex(text)
+	if loc == nil {
+		return nil
+	}
+	headers, body := splitForwardBlock(text[loc[1]:])
+	from := headerValue(headers, "From")
+	if from == "" {
+		return nil
+	}
+
+	p := &gmail.MessagePart{MimeType: "multipart/mixed"}
+	p.Headers = append(p.Headers, &gmail.MessagePartHeader{Name: "From", Value: forwardFrom(from)})
+	if v := headerValue(headers, "Subject"); v != "" {
+		p.Headers = append(p.Headers, &gmail.MessagePartHeader{Name: "Subject", Value: v})
+	}
+	if v := firstNonEmpty(headerValue(headers, "Date"), headerValue(headers, "Sent")); v != "" {
+		p.Headers = append(p.Headers, &gmail.MessagePartHeader{Name: "Date", Value: v})
+	}
+	p.Parts = append(p.Parts, textPart("text/plain", body))
+	if c.HTML != "" {
+		// The quoted header block stays in the HTML; only the
+		// forwarder's note above the marker is dropped.
+		if i := forwardMarkerHTML.FindStringIndex(c.HTML); i != nil {
+			p.Parts = append(p.Parts, textPart("text/html", c.HTML[i[1]:]))
+		}
+	}
+	for _, part := range attachmentParts(msg.Payload) {
+		p.Parts = append(p.Parts, part)
+	}
+	return p
+}
+
+// splitForwardBlock separates the header lines right below the marker from the body.
+func splitForwardBlock(s string) (map[string]string, string) {
+	headers := make(map[string]string)
+	lines := strings.Split(strings.TrimLeft(s, "\r\n"), "\n")
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		if strings.TrimSpace(strings.TrimLeft(line, ">")) == "" {
+			if len(headers) > 0 {
+				i++
+				break
+			}
+			continue
+		}
+		m := forwardHeader.FindStringSubmatch(line)
+		if m == nil {
+			break
+		}
+		name := strings.ToLower(m[1])
+		if _, seen := headers[name]; !seen {
+			headers[name] = strings.TrimSpace(m[2])
+		}
+	}
+	return headers, strings.Join(lines[i:], "\n")
+}
+
+func headerValue(headers map[string]string, name string) string {
+	return headers[strings.ToLower(name)]
+}
+
+// forwardFrom cleans up a quoted From value, e.g. "Bay Electric
+// <noreply@bayelectric.example>" or Outlook's "Bay Electric [mailto:...]".
+func forwardFrom(v string) string {
+	v = strings.ReplaceAll(v, "[mailto:", "<")
+	v = strings.ReplaceAll(v, "]", ">")
+	if a, err := mail.ParseAddress(v); err == nil {
+		return a.String()
+	}
+	return v
+}
+
+func textPart(mimeType, text string) *gmail.MessagePart {
+	return &gmail.MessagePart{
+		MimeType: mimeType,
+		Headers:  []*gmail.MessagePartHeader{{Name: "Content-Type", Value: mimeType + "; charset=UTF-8"}},
+		Body:     &gmail.MessagePartBody{Data: base64.URLEncoding.EncodeToString([]byte(text)), Size: int64(len(text))},
+	}
+}
+
+// attachmentParts returns the non-body leaf parts of p.
+func attachmentParts(p *gmail.MessagePart) []*gmail.MessagePart {
+	if strings.HasPrefix(strings.ToLower(p.MimeType), "multipart/") {
+		var out []*gmail.MessagePart
+		for _, child := range p.Parts {
+			out = append(out, attachmentParts(child)...)
+		}
+		return out
+	}
+	mimeType := strings.ToLower(p.MimeType)
+	if (mimeType == "text/html" || mimeType == "text/plain") && !isAttachment(p) {
+		return nil
+	}
+	if p.Filename != "" || (p.Body != nil && p.Body.AttachmentId != "") {
+		return []*gmail.MessagePart{p}
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
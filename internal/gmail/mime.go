@@ -0,0 +1,312 @@
+package gmail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"google.golang.org/api/gmail/v1"
+)
+
+// Content is the readable content of a message, gathered from every MIME part.
+type Content struct {
+	HTML        string
+	Plain       string
+	Attachments []Attachment
+}
+
+// Attachment describes a part that is not an inline text body.
+type Attachment struct {
+	PartID       string
+	Filename     string
+	MimeType     string
+	AttachmentID string // set when the data must be fetched via the attachments API
+	Size         int64
+	Data         []byte // set when the part body was returned inline
+}
+
+// GetMessageContent walks the full MIME tree of msg and returns the first inline
+// HTML and plain-text bodies found at any depth, decoded to UTF-8, along with
+// every attachment. Forwarded message/rfc822 parts are walked as well.
+func GetMessageContent(msg *gmail.Message) *Content {
+	c := &Content{}
+	if msg == nil || msg.Payload == nil {
+		return c
+	}
+	walkPart(msg.Payload, c)
+	return c
+}
+
+func walkPart(p *gmail.MessagePart, c *Content) {
+	mimeType := strings.ToLower(p.MimeType)
+	switch {
+	case strings.HasPrefix(mimeType, "multipart/"):
+		for _, child := range p.Parts {
+			walkPart(child, c)
+		}
+	case mimeType == "message/rfc822":
+		if len(p.Parts) > 0 {
+			for _, child := range p.Parts {
+				walkPart(child, c)
+			}
+			return
+		}
+		if raw := partData(p); raw != nil {
+			if inner, err := ParseRaw(raw); err == nil {
+				walkPart(inner.Payload, c)
+				return
+			}
+		}
+		addAttachment(p, c)
+	case (mimeType == "text/html" || mimeType == "text/plain") && !isAttachment(p):
+		data := partData(p)
+		if data == nil {
+			return
+		}
+		text := decodeCharset(data, partCharset(p))
+		if mimeType == "text/html" && c.HTML == "" {
+			c.HTML = text
+		}
+		if mimeType == "text/plain" && c.Plain == "" {
+			c.Plain = text
+		}
+	default:
+		if p.Filename != "" || (p.Body != nil && p.Body.AttachmentId != "") {
+			addAttachment(p, c)
+		}
+	}
+}
+
+func addAttachment(p *gmail.MessagePart, c *Content) {
+	a := Attachment{PartID: p.PartId, Filename: p.Filename, MimeType: p.MimeType}
+	if p.Body != nil {
+		a.AttachmentID = p.Body.AttachmentId
+		a.Size = p.Body.Size
+	}
+	if a.AttachmentID == "" {
+		a.Data = partData(p)
+	}
+	c.Attachments = append(c.Attachments, a)
+}
+
+// isAttachment reports whether a text part is an attachment rather than a body.
+func isAttachment(p *gmail.MessagePart) bool {
+	if p.Filename != "" {
+		return true
+	}
+	if p.Body != nil && p.Body.AttachmentId != "" {
+		return true
+	}
+	disp, _, err := mime.ParseMediaType(partHeader(p, "Content-Disposition"))
+	return err == nil && disp == "attachment"
+}
+
+// partData returns the decoded inline body bytes of p, or nil if there are none.
+func partData(p *gmail.MessagePart) []byte {
+	if p.Body == nil || p.Body.Data == "" {
+		return nil
+	}
+	data, err := base64.URLEncoding.DecodeString(padBase64(p.Body.Data))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func partHeader(p *gmail.MessagePart, name string) string {
+	for _, h := range p.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func partCharset(p *gmail.MessagePart) string {
+	_, params, err := mime.ParseMediaType(partHeader(p, "Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
+
+// decodeCharset converts data in the named charset to UTF-8.
+// Data that is already valid UTF-8 is returned unchanged; otherwise an
+// unknown or missing charset is treated as Windows-1252, the usual culprit.
+func decodeCharset(data []byte, charset string) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		enc, _ = htmlindex.Get("windows-1252")
+	}
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+func padBase64(s string) string {
+	switch len(s) % 4 {
+	case 2:
+		return s + "=="
+	case 3:
+		return s + "="
+	}
+	return s
+}
+
+// ReadMessageFile loads a message saved on disk, either as a raw RFC 822
+// .eml file or as the JSON the Gmail API returns for messages.get (format=full).
+func ReadMessageFile(path string) (*gmail.Message, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var msg gmail.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return &msg, nil
+	}
+	msg, err := ParseRaw(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return msg, nil
+}
+
+// ParseRaw parses an RFC 822 message into the same shape the Gmail API returns
+// for format=full: headers are decoded, transfer encodings are undone and every
+// part body is base64url encoded in Body.Data.
+func ParseRaw(raw []byte) (*gmail.Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("read message: %w", err)
+	}
+	body, err := io.ReadAll(m.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	payload, err := parsePart(mail.Header(m.Header), body, "")
+	if err != nil {
+		return nil, err
+	}
+	return &gmail.Message{Payload: payload, SizeEstimate: int64(len(raw))}, nil
+}
+
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, err
+		}
+		return enc.NewDecoder().Reader(input), nil
+	},
+}
+
+func parsePart(header map[string][]string, body []byte, partID string) (*gmail.MessagePart, error) {
+	p := &gmail.MessagePart{PartId: partID, MimeType: "text/plain"}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range header[name] {
+			if dec, err := wordDecoder.DecodeHeader(v); err == nil {
+				v = dec
+			}
+			p.Headers = append(p.Headers, &gmail.MessagePartHeader{Name: name, Value: v})
+		}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(partHeader(p, "Content-Type"))
+	if err == nil {
+		p.MimeType = mediaType
+	}
+	if _, dparams, err := mime.ParseMediaType(partHeader(p, "Content-Disposition")); err == nil {
+		p.Filename = dparams["filename"]
+	}
+	if p.Filename == "" {
+		p.Filename = params["name"]
+	}
+
+	if strings.HasPrefix(p.MimeType, "multipart/") && params["boundary"] != "" {
+		p.Body = &gmail.MessagePartBody{}
+		r := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for i := 0; ; i++ {
+			part, err := r.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("multipart: %w", err)
+			}
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return nil, fmt.Errorf("multipart: %w", err)
+			}
+			childID := fmt.Sprintf("%d", i)
+			if partID != "" {
+				childID = partID + "." + childID
+			}
+			child, err := parsePart(part.Header, data, childID)
+			if err != nil {
+				return nil, err
+			}
+			p.Parts = append(p.Parts, child)
+		}
+		return p, nil
+	}
+
+	data, err := decodeTransfer(body, partHeader(p, "Content-Transfer-Encoding"))
+	if err != nil {
+		return nil, fmt.Errorf("part %s: %w", partID, err)
+	}
+	p.Body = &gmail.MessagePartBody{
+		Data: base64.URLEncoding.EncodeToString(data),
+		Size: int64(len(data)),
+	}
+	return p, nil
+}
+
+func decodeTransfer(body []byte, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		cleaned := strings.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+				return -1
+			}
+			return r
+		}, string(body))
+		return base64.StdEncoding.DecodeString(cleaned)
+	case "quoted-printable":
+		return io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+	default:
+		return body, nil
+	}
+}
+
+// Header returns the first top-level header with the given name (case-insensitive).
+func Header(msg *gmail.Message, name string) string {
+	if msg == nil || msg.Payload == nil {
+		return ""
+	}
+	return partHeader(msg.Payload, name)
+}
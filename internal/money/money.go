@@ -0,0 +1,141 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount in a currency's minor units (e.g. cents for USD).
+type Money struct {
+	Minor    int64  `firestore:"minor" json:"minor"`
+	Currency string `firestore:"currency" json:"currency"` // ISO 4217 code
+}
+
+// zeroDecimal lists currencies without minor units.
+var zeroDecimal = map[string]bool{
+	"JPY": true, "KRW": true, "VND": true, "CLP": true, "ISK": true,
+}
+
+// Exponent returns the number of minor-unit digits for a currency (2 for most).
+func Exponent(currency string) int {
+	if zeroDecimal[strings.ToUpper(currency)] {
+		return 0
+	}
+	return 2
+}
+
+// New returns an amount of minor units in currency.
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: strings.ToUpper(currency)}
+}
+
+// Parse reads a decimal amount in currency. Both US ("1,234.56") and
+// European ("1.234,56") separators are accepted: when both "." and "," appear
+// the last one is the decimal separator, and a lone separator followed by
+// exactly three digits is read as a thousands separator. Apostrophes are
+// accepted as thousands separators ("1'234.56"). Extra fraction digits are rejected.
+func Parse(s, currency string) (Money, error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "'", ""))
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac := splitDecimal(s)
+	exp := Exponent(currency)
+	if whole == "" && frac == "" {
+		return Money{}, fmt.Errorf("money: empty amount")
+	}
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("money: %q has more than %d decimal places", s, exp)
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+	if whole == "" {
+		whole = "0"
+	}
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("money: %w", err)
+	}
+	if neg {
+		minor = -minor
+	}
+	return New(minor, currency), nil
+}
+
+// splitDecimal returns the integer digits and fraction digits of s with
+// thousands separators removed.
+func splitDecimal(s string) (whole, frac string) {
+	dot := strings.LastIndex(s, ".")
+	comma := strings.LastIndex(s, ",")
+	sep := -1
+	switch {
+	case dot >= 0 && comma >= 0:
+		sep = max(dot, comma)
+	case dot >= 0 || comma >= 0:
+		sep = max(dot, comma)
+		single := strings.Count(s, s[sep:sep+1]) == 1
+		if !single || len(s)-sep-1 == 3 {
+			sep = -1 // thousands separator, e.g. "1,234" or "1.234.567"
+		}
+	}
+	if sep < 0 {
+		return stripSeparators(s), ""
+	}
+	return stripSeparators(s[:sep]), s[sep+1:]
+}
+
+func stripSeparators(s string) string {
+	return strings.NewReplacer(",", "", ".", "").Replace(s)
+}
+
+// FromFloat converts a float amount in major units, rounding to the nearest
+// minor unit. Only for reading legacy float64 values and configured limits.
+func FromFloat(f float64, currency string) Money {
+	scale := math.Pow10(Exponent(currency))
+	return New(int64(math.Round(f*scale)), currency)
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool { return m.Minor == 0 }
+
+// Add returns m + o. Both amounts must share a currency.
+func (m Money) Add(o Money) Money { return Money{Minor: m.Minor + o.Minor, Currency: m.Currency} }
+
+// Sub returns m - o. Both amounts must share a currency.
+func (m Money) Sub(o Money) Money { return Money{Minor: m.Minor - o.Minor, Currency: m.Currency} }
+
+// symbols maps currencies to the prefix used when formatting amounts.
+var symbols = map[string]string{
+	"USD": "$", "EUR": "€", "GBP": "£", "JPY": "¥",
+	"CAD": "CA$", "AUD": "A$", "NZD": "NZ$", "MXN": "MX$", "INR": "₹",
+}
+
+// Format formats the amount for people, e.g. "$1234.56", "€9.99" or "12.00 CHF".
+func (m Money) Format() string {
+	if sym, ok := symbols[m.Currency]; ok {
+		if m.Minor < 0 {
+			return "-" + sym + New(-m.Minor, m.Currency).String()
+		}
+		return sym + m.String()
+	}
+	if m.Currency == "" {
+		return m.String()
+	}
+	return m.String() + " " + m.Currency
+}
+
+// String formats the amount as a plain decimal, e.g. "1234.56".
+func (m Money) String() string {
+	exp := Exponent(m.Currency)
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	if exp == 0 {
+		return sign + strconv.FormatInt(minor, 10)
+	}
+	scale := int64(math.Pow10(exp))
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/scale, exp, minor%scale)
+}
@@ -3,10 +3,10 @@ package notify
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	"github.com/akksell/rbn/internal/config"
 	"github.com/akksell/rbn/internal/gmail"
+	"github.com/akksell/rbn/internal/money"
 	"github.com/akksell/rbn/internal/store"
 )
 
@@ -21,23 +21,103 @@ func NewSender(cfg *config.Config, gmailClient *gmail.Client) *Sender {
 	return &Sender{cfg: cfg, gmail: gmailClient}
 }
 
-// SendBillNotification sends an email to the roommate with their share and bill details.
-// originalBody can be the raw message or a snippet to include.
-func (s *Sender) SendBillNotification(ctx context.Context, to store.Roommate, billerCompany string, amount float64, dueDate string, originalBody string) error {
-	subject := fmt.Sprintf("Bill split: %s - Your share $%s", billerCompany, formatAmount(amount))
-	body := fmt.Sprintf("Your share for the bill from %s is $%s.\n", billerCompany, formatAmount(amount))
+// Notice is one roommate's share of a bill.
+type Notice struct {
+	Bill    *store.Bill
+	Share   money.Money
+	Paid    money.Money // already paid toward Share, carried over from a superseded bill
+	DueDate string      // formatted in the household time zone; empty if unknown
+	PayTo   string      // name of the roommate who pays the biller, from the biller directory
+	Autopay bool        // the biller charges PayTo automatically
+}
+
+// SendBillNotification sends an email to the roommate with their share and bill details,
+// followed by the bill's message excerpt.
+func (s *Sender) SendBillNotification(ctx context.Context, to store.Roommate, n Notice) error {
+	b, amount, dueDate := n.Bill, n.Share, n.DueDate
+	billerCompany := b.BillerCompany
+	subject := fmt.Sprintf("Bill split: %s - Your share %s", billerCompany, formatAmount(amount))
+	if b.Supersedes != "" {
+		subject = fmt.Sprintf("Corrected bill: %s - Your share %s", billerCompany, formatAmount(amount))
+	}
+	body := fmt.Sprintf("Your share for the bill from %s is %s.\n", billerCompany, formatAmount(amount))
 	if to.DisplayName != "" {
-		body = fmt.Sprintf("Hi %s,\n\nYour share for the bill from %s is $%s.\n", to.DisplayName, billerCompany, formatAmount(amount))
+		body = fmt.Sprintf("Hi %s,\n\nYour share for the bill from %s is %s.\n", to.DisplayName, billerCompany, formatAmount(amount))
+	}
+	if b.Supersedes != "" {
+		body += "This corrected statement replaces the earlier bill.\n"
+	}
+	if !n.Paid.IsZero() {
+		owed := amount.Sub(n.Paid)
+		switch {
+		case owed.Minor > 0:
+			body += fmt.Sprintf("You already paid %s; %s remains.\n", formatAmount(n.Paid), formatAmount(owed))
+		case owed.Minor < 0:
+			body += fmt.Sprintf("You already paid %s, which is %s more than your share.\n", formatAmount(n.Paid), formatAmount(money.New(-owed.Minor, owed.Currency)))
+		default:
+			body += "You have already paid your share.\n"
+		}
 	}
 	if dueDate != "" {
 		body += fmt.Sprintf("Due date: %s\n", dueDate)
 	}
+	if b.AccountNumber != "" {
+		body += fmt.Sprintf("Account: %s\n", b.AccountNumber)
+	}
+	if b.ServiceAddress != "" {
+		body += fmt.Sprintf("Service address: %s\n", b.ServiceAddress)
+	}
+	switch {
+	case n.PayTo != "" && n.Autopay:
+		body += fmt.Sprintf("This bill is paid automatically by %s; please pay them your share.\n", n.PayTo)
+	case n.PayTo != "":
+		body += fmt.Sprintf("%s pays this bill; please pay them your share.\n", n.PayTo)
+	}
+	if len(b.LineItems) > 0 {
+		body += "\nItemized charges:\n"
+		for _, li := range b.LineItems {
+			body += fmt.Sprintf("  %s: %s%s\n", li.Description, formatAmount(li.Amount), itemNote(li, to.ID))
+		}
+	}
 	body += "\n--- Original message excerpt ---\n"
-	body += originalBody
+	body += b.Excerpt
 
 	return s.gmail.SendMessage(ctx, s.cfg.GmailInboxUser, to.Email, subject, body)
 }
 
-func formatAmount(a float64) string {
-	return strconv.FormatFloat(a, 'f', 2, 64)
+// SendAdminAlert tells the admin that a bill failed validation and is held
+// for review instead of being sent to roommates.
+func (s *Sender) SendAdminAlert(ctx context.Context, to string, b *store.Bill) error {
+	subject := fmt.Sprintf("Bill held for review: %s %s", b.BillerCompany, formatAmount(b.TotalAmount))
+	body := fmt.Sprintf("A bill from %s for %s was held for review and has not been sent to roommates.\n\n", b.BillerCompany, formatAmount(b.TotalAmount))
+	body += "Reasons:\n"
+	for _, r := range b.ReviewReasons {
+		body += fmt.Sprintf("  - %s\n", r)
+	}
+	body += fmt.Sprintf("\nBill ID: %s\n", b.ID)
+	body += fmt.Sprintf("Correct it with PATCH /bills/%s or approve it with POST /bills/%s/approve.\n", b.ID, b.ID)
+	body += "\n--- Original message excerpt ---\n"
+	body += b.Excerpt
+
+	return s.gmail.SendMessage(ctx, s.cfg.GmailInboxUser, to, subject, body)
+}
+
+// itemNote says how a line item assigned to some roommates affects to's share.
+func itemNote(li store.LineItem, roommateID string) string {
+	if len(li.RoommateIDs) == 0 {
+		return ""
+	}
+	for _, id := range li.RoommateIDs {
+		if id == roommateID {
+			if len(li.RoommateIDs) == 1 {
+				return " (yours alone)"
+			}
+			return fmt.Sprintf(" (shared by %d roommates, including you)", len(li.RoommateIDs))
+		}
+	}
+	return " (not included in your share)"
+}
+
+func formatAmount(a money.Money) string {
+	return a.Format()
 }
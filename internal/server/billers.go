@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/akksell/rbn/internal/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// billers handles GET and POST /billers.
+func (s *Server) billers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.store.ListBillers(ctx)
+		if err != nil {
+			log.Printf("list billers: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, list)
+	case http.MethodPost:
+		b, ok := decodeBiller(w, r)
+		if !ok {
+			return
+		}
+		b.ID = ""
+		if err := s.store.SaveBiller(ctx, b); err != nil {
+			log.Printf("save biller: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		// Headers set after WriteHeader are dropped, so set the type first.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, b)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// biller handles GET, PUT and DELETE /billers/{billerId}.
+func (s *Server) biller(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/billers/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+
+	existing, err := s.store.GetBiller(ctx, id)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("get biller: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, existing)
+	case http.MethodPut:
+		b, ok := decodeBiller(w, r)
+		if !ok {
+			return
+		}
+		b.ID = id
+		if err := s.store.SaveBiller(ctx, b); err != nil {
+			log.Printf("save biller: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, b)
+	case http.MethodDelete:
+		if err := s.store.DeleteBiller(ctx, id); err != nil {
+			log.Printf("delete biller: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeBiller reads and validates a directory entry, writing the error response if invalid.
+func decodeBiller(w http.ResponseWriter, r *http.Request) (*store.Biller, bool) {
+	var b store.Biller
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return nil, false
+	}
+	b.Name = strings.TrimSpace(b.Name)
+	senders := b.Senders[:0]
+	for _, sender := range b.Senders {
+		if sender = strings.ToLower(strings.TrimSpace(sender)); sender != "" {
+			senders = append(senders, sender)
+		}
+	}
+	b.Senders = senders
+
+	switch {
+	case b.Name == "":
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return nil, false
+	case len(b.Senders) == 0:
+		http.Error(w, "at least one sender address or domain is required", http.StatusBadRequest)
+		return nil, false
+	case b.Cadence != "" && store.CadenceMonths(b.Cadence) == 0:
+		http.Error(w, "unknown cadence "+b.Cadence, http.StatusBadRequest)
+		return nil, false
+	}
+	return &b, true
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/akksell/rbn/internal/bill"
+	"github.com/akksell/rbn/internal/gmail"
+	"github.com/akksell/rbn/internal/store"
+)
+
+// recordBillerPayment handles a payment confirmation: the open bill it pays
+// is marked as paid to the biller. Roommates' debts are unaffected.
+func (s *Server) recordBillerPayment(ctx context.Context, messageID string, src *bill.Source, biller *store.Biller) error {
+	payment, _ := bill.ExtractPayment(src, s.cfg.Location)
+
+	open, err := s.store.ListBillerUnpaid(ctx)
+	if err != nil {
+		return err
+	}
+	name := bill.SenderName(gmail.Header(src.Message, "From"))
+	if biller != nil {
+		name = biller.Name
+	}
+	b := matchPayment(open, payment, biller, name)
+	if b == nil {
+		log.Printf("message %s: payment confirmation from %s matches no open bill", messageID, name)
+		return nil
+	}
+
+	paidAt := payment.PaidOn
+	if paidAt.IsZero() {
+		paidAt = time.Now()
+	}
+	log.Printf("message %s: %s confirmed payment of bill %s", messageID, name, b.ID)
+	return s.store.MarkBillerPaid(ctx, b.ID, paidAt, messageID)
+}
+
+// matchPayment returns the oldest open bill from the same biller whose account
+// and amount agree with the payment. Fields the confirmation doesn't state
+// are not compared, but an amount-less, account-less confirmation only
+// matches when the biller has exactly one open bill.
+func matchPayment(open []store.Bill, p *bill.Payment, biller *store.Biller, name string) *store.Bill {
+	var candidates []*store.Bill
+	for i := range open {
+		b := &open[i]
+		if biller != nil && b.BillerID != "" {
+			if b.BillerID != biller.ID {
+				continue
+			}
+		} else if !strings.EqualFold(b.BillerCompany, name) {
+			continue
+		}
+		if p.AccountNumber != "" && b.AccountNumber != "" && bill.MaskAccount(p.AccountNumber) != b.AccountNumber {
+			continue
+		}
+		if !p.Amount.IsZero() && p.Amount != b.TotalAmount {
+			continue
+		}
+		candidates = append(candidates, b)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	if p.Amount.IsZero() && p.AccountNumber == "" && len(candidates) > 1 {
+		return nil
+	}
+	return candidates[0]
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/akksell/rbn/internal/bill"
+	"github.com/akksell/rbn/internal/money"
+	"github.com/akksell/rbn/internal/store"
+)
+
+// relatedMonths is how far back reminders and corrections are matched to bills.
+const relatedMonths = 4
+
+// relatedBill returns the most recent bill that b repeats (a reminder or a
+// resent statement) or, for a corrected statement, replaces. Bills match on
+// biller and account; the billing period must agree when both know it. A
+// repeat must also have the same amount, and a new statement without a
+// billing period the same due date, so next month's equal bill isn't taken
+// for a duplicate.
+func relatedBill(recent []store.Bill, b *store.Bill, kind bill.Kind) *store.Bill {
+	for i := len(recent) - 1; i >= 0; i-- {
+		r := &recent[i]
+		if r.Status == store.BillStatusSuperseded || r.GmailMessageID == b.GmailMessageID {
+			continue
+		}
+		if !sameBiller(r, b) || r.AccountNumber != b.AccountNumber {
+			continue
+		}
+		periodKnown := r.BillingPeriod != "" && b.BillingPeriod != ""
+		if periodKnown && !strings.EqualFold(r.BillingPeriod, b.BillingPeriod) {
+			continue
+		}
+		if kind == bill.KindCorrection {
+			return r
+		}
+		if r.TotalAmount != b.TotalAmount {
+			continue
+		}
+		if kind == bill.KindBill && !periodKnown && (b.DueDate.IsZero() || !r.DueDate.Equal(b.DueDate)) {
+			continue
+		}
+		return r
+	}
+	return nil
+}
+
+// receivedSince returns the bills received at or after t.
+func receivedSince(bills []store.Bill, t time.Time) []store.Bill {
+	var out []store.Bill
+	for _, b := range bills {
+		if !b.DateReceived.Before(t) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// billerHistory returns the split bills from b's biller, for validating b's amount.
+func billerHistory(bills []store.Bill, b *store.Bill) []store.Bill {
+	var out []store.Bill
+	for i := range bills {
+		h := &bills[i]
+		switch h.Status {
+		case store.BillStatusNeedsReview, store.BillStatusSuperseded:
+			continue
+		}
+		if sameBiller(h, b) && h.GmailMessageID != b.GmailMessageID {
+			out = append(out, *h)
+		}
+	}
+	return out
+}
+
+func sameBiller(a, b *store.Bill) bool {
+	if a.BillerID != "" && b.BillerID != "" {
+		return a.BillerID == b.BillerID
+	}
+	return strings.EqualFold(a.BillerCompany, b.BillerCompany)
+}
+
+// debtors returns the roommates who owed the debts, in debt order. Roommates
+// no longer on file are returned with only their ID so their share is kept.
+func debtors(roommates []store.Roommate, debts []store.Debt) []store.Roommate {
+	out := make([]store.Roommate, len(debts))
+	for i, d := range debts {
+		out[i] = store.Roommate{ID: d.RoommateID}
+		for _, r := range roommates {
+			if r.ID == d.RoommateID {
+				out[i] = r
+				break
+			}
+		}
+	}
+	return out
+}
+
+// carryPayments credits each new debt with what its roommate paid toward the
+// superseded bill, and returns the resulting bill status.
+func carryPayments(debts, previous []store.Debt) string {
+	paid := 0
+	for i := range debts {
+		d := &debts[i]
+		for _, p := range previous {
+			if p.RoommateID != d.RoommateID {
+				continue
+			}
+			d.PaidAmount = p.PaidAmount
+			if p.Status == store.DebtStatusPaid && p.PaidAmount.IsZero() {
+				d.PaidAmount = p.Amount
+			}
+			d.PaidAt, d.PaidBy = p.PaidAt, p.PaidBy
+			break
+		}
+		if d.PaidAmount.IsZero() {
+			d.PaidAmount = money.New(0, d.Amount.Currency)
+			d.PaidAt, d.PaidBy = nil, ""
+			continue
+		}
+		if d.Owed().Minor <= 0 {
+			d.Status = store.DebtStatusPaid
+			paid++
+		}
+	}
+	switch {
+	case paid == len(debts) && paid > 0:
+		return store.BillStatusPaid
+	case paid > 0:
+		return store.BillStatusPartial
+	}
+	return store.BillStatusUnpaid
+}
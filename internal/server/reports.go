@@ -0,0 +1,163 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/akksell/rbn/internal/money"
+	"github.com/akksell/rbn/internal/store"
+)
+
+// BillerReport summarizes the last year of bills for one directory entry.
+type BillerReport struct {
+	BillerID     string        `json:"billerId"`
+	Name         string        `json:"name"`
+	Category     string        `json:"category,omitempty"`
+	Payer        string        `json:"payer,omitempty"`
+	Autopay      bool          `json:"autopay"`
+	Cadence      string        `json:"cadence,omitempty"`
+	BillCount    int           `json:"billCount"` // split bills; excludes bills under review and superseded ones
+	Totals       []money.Money `json:"totals"`    // one per currency billed
+	LastBill     *time.Time    `json:"lastBill,omitempty"`
+	NextExpected *time.Time    `json:"nextExpected,omitempty"`
+	Overdue      bool          `json:"overdue"` // no bill has arrived by NextExpected
+}
+
+// billerReport handles GET /reports/billers: per-biller totals over the last 12 months,
+// with the next bill expected from the biller's cadence.
+func (s *Server) billerReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	now := time.Now()
+
+	billers, err := s.store.ListBillers(ctx)
+	if err != nil {
+		log.Printf("list billers: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	roommates, err := s.store.ListActiveRoommates(ctx)
+	if err != nil {
+		log.Printf("list roommates: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	bills, err := s.store.ListBillsSince(ctx, now.AddDate(-1, 0, 0))
+	if err != nil {
+		log.Printf("list bills: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	byBiller := make(map[string][]store.Bill)
+	for _, b := range bills {
+		if b.BillerID != "" {
+			byBiller[b.BillerID] = append(byBiller[b.BillerID], b)
+		}
+	}
+
+	reports := make([]BillerReport, 0, len(billers))
+	for _, entry := range billers {
+		rep := BillerReport{
+			BillerID: entry.ID,
+			Name:     entry.Name,
+			Category: entry.Category,
+			Payer:    roommateName(roommates, entry.PayerRoommateID),
+			Autopay:  entry.Autopay,
+			Cadence:  entry.Cadence,
+			Totals:   []money.Money{},
+		}
+		for _, b := range byBiller[entry.ID] {
+			if received := b.DateReceived; rep.LastBill == nil || received.After(*rep.LastBill) {
+				rep.LastBill = &received
+			}
+			// Amounts under review may be misread, and corrected bills
+			// would otherwise be counted twice.
+			if b.Status == store.BillStatusNeedsReview || b.Status == store.BillStatusSuperseded {
+				continue
+			}
+			rep.BillCount++
+			rep.Totals = addTotal(rep.Totals, b.TotalAmount)
+		}
+		if months := store.CadenceMonths(entry.Cadence); months > 0 && rep.LastBill != nil {
+			next := rep.LastBill.AddDate(0, months, 0)
+			rep.NextExpected = &next
+			rep.Overdue = now.After(next)
+		}
+		reports = append(reports, rep)
+	}
+	writeJSON(w, reports)
+}
+
+// addTotal adds m to the running total for its currency.
+func addTotal(totals []money.Money, m money.Money) []money.Money {
+	for i, t := range totals {
+		if t.Currency == m.Currency {
+			totals[i] = t.Add(m)
+			return totals
+		}
+	}
+	return append(totals, m)
+}
+
+// UsagePoint is one bill's usage in a UsageReport.
+type UsagePoint struct {
+	BillID      string      `json:"billId"`
+	PeriodStart time.Time   `json:"periodStart"`
+	PeriodEnd   time.Time   `json:"periodEnd"`
+	Quantity    float64     `json:"quantity"`
+	Total       money.Money `json:"total"`
+}
+
+// UsageReport is the usage history of one unit from one biller.
+type UsageReport struct {
+	Biller string       `json:"biller"`
+	Unit   string       `json:"unit"`
+	Points []UsagePoint `json:"points"` // oldest first
+}
+
+// usageReport handles GET /reports/usage[?months=N]: usage figures per biller
+// and unit over the last N months (default 12).
+func (s *Server) usageReport(w http.ResponseWriter, r *http.Request) {
+	months := 12
+	if v := r.URL.Query().Get("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		months = n
+	}
+	bills, err := s.store.ListBillsSince(r.Context(), time.Now().AddDate(0, -months, 0))
+	if err != nil {
+		log.Printf("list bills: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	reports := []UsageReport{}
+	index := make(map[[2]string]int)
+	for _, b := range bills {
+		if b.Status == store.BillStatusSuperseded {
+			continue
+		}
+		for _, u := range b.Usage {
+			key := [2]string{b.BillerCompany, u.Unit}
+			i, ok := index[key]
+			if !ok {
+				i = len(reports)
+				index[key] = i
+				reports = append(reports, UsageReport{Biller: b.BillerCompany, Unit: u.Unit})
+			}
+			reports[i].Points = append(reports[i].Points, UsagePoint{
+				BillID:      b.ID,
+				PeriodStart: b.PeriodStart,
+				PeriodEnd:   b.PeriodEnd,
+				Quantity:    u.Quantity,
+				Total:       b.TotalAmount,
+			})
+		}
+	}
+	writeJSON(w, reports)
+}
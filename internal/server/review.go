@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akksell/rbn/internal/bill"
+	"github.com/akksell/rbn/internal/money"
+	"github.com/akksell/rbn/internal/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// listReview handles GET /bills/review: bills held because extraction confidence was low.
+func (s *Server) listReview(w http.ResponseWriter, r *http.Request) {
+	bills, err := s.store.ListBillsByStatus(r.Context(), store.BillStatusNeedsReview)
+	if err != nil {
+		log.Printf("list review: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, bills)
+}
+
+// BillCorrection is the body of PATCH /bills/{billId}. Omitted fields are unchanged.
+type BillCorrection struct {
+	Total    string `json:"total,omitempty"`    // decimal amount, e.g. "123.45"
+	Currency string `json:"currency,omitempty"` // defaults to the bill's currency
+	DueDate  string `json:"dueDate,omitempty"`  // any format bill.ParseDate accepts
+}
+
+// correctBill handles PATCH /bills/{billId} for a bill in review.
+func (s *Server) correctBill(w http.ResponseWriter, r *http.Request) {
+	billID := strings.TrimPrefix(r.URL.Path, "/bills/")
+	ctx := r.Context()
+
+	existing, ok := s.reviewBill(w, r, billID)
+	if !ok {
+		return
+	}
+
+	var body BillCorrection
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	var total *money.Money
+	if body.Total != "" {
+		currency := body.Currency
+		if currency == "" {
+			currency = existing.TotalAmount.Currency
+		}
+		m, err := money.Parse(body.Total, currency)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		total = &m
+	}
+	var due *time.Time
+	if body.DueDate != "" {
+		t, err := bill.ParseDate(body.DueDate, s.cfg.Location)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		due = &t
+	}
+
+	if err := s.store.CorrectBill(ctx, billID, total, due); err != nil {
+		log.Printf("correct bill: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	updated, err := s.store.GetBill(ctx, billID)
+	if err != nil {
+		log.Printf("get bill: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, updated)
+}
+
+// approveBill handles POST /bills/{billId}/approve: the bill is split and
+// roommates are notified as if extraction had been confident.
+func (s *Server) approveBill(w http.ResponseWriter, r *http.Request) {
+	billID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/bills/"), "/approve")
+
+	b, ok := s.reviewBill(w, r, billID)
+	if !ok {
+		return
+	}
+	if err := s.splitAndNotify(r.Context(), b); err != nil {
+		if errors.Is(err, errNoDebtors) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("approve bill: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, b)
+}
+
+// reviewBill loads a bill and checks it is awaiting review, writing the error response if not.
+func (s *Server) reviewBill(w http.ResponseWriter, r *http.Request, billID string) (*store.Bill, bool) {
+	if billID == "" || strings.Contains(billID, "/") {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return nil, false
+	}
+	b, err := s.store.GetBill(r.Context(), billID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("get bill: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if b.Status != store.BillStatusNeedsReview {
+		http.Error(w, "bill is not awaiting review", http.StatusConflict)
+		return nil, false
+	}
+	return b, true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("write json: %v", err)
+	}
+}
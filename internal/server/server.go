@@ -3,8 +3,12 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/mail"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,6 +20,7 @@ import (
 	"github.com/akksell/rbn/internal/pubsub"
 	"github.com/akksell/rbn/internal/split"
 	"github.com/akksell/rbn/internal/store"
+	"github.com/akksell/rbn/internal/validate"
 )
 
 // Server is the HTTP handler for Pub/Sub push and health.
@@ -23,13 +28,25 @@ type Server struct {
 	cfg     *config.Config
 	store   *store.Store
 	gmail   *gmail.Client
-	extract *bill.Extractor
+	extract *bill.Chain
 	notify  *notify.Sender
+
+	splitRules []split.Rule
+	itemRules  []split.ItemRule
 }
 
 // New builds the HTTP server with push and health handlers.
-func New(cfg *config.Config, st *store.Store, gm *gmail.Client, ext *bill.Extractor, n *notify.Sender) (*Server, error) {
-	return &Server{cfg: cfg, store: st, gmail: gm, extract: ext, notify: n}, nil
+// ext is the extractor chain every matching message is run through.
+func New(cfg *config.Config, st *store.Store, gm *gmail.Client, ext *bill.Chain, n *notify.Sender) (*Server, error) {
+	splitRules, err := split.CompileRules(cfg.Split.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("split: %w", err)
+	}
+	itemRules, err := split.CompileItemRules(cfg.Split.LineItems)
+	if err != nil {
+		return nil, fmt.Errorf("split: %w", err)
+	}
+	return &Server{cfg: cfg, store: st, gmail: gm, extract: ext, notify: n, splitRules: splitRules, itemRules: itemRules}, nil
 }
 
 // ServeHTTP routes requests.
@@ -49,11 +66,42 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			s.health(w, r)
 			return
 		}
+	case r.URL.Path == "/bills/review":
+		if r.Method == http.MethodGet {
+			s.listReview(w, r)
+			return
+		}
+	case strings.HasPrefix(r.URL.Path, "/bills/") && strings.HasSuffix(r.URL.Path, "/approve"):
+		if r.Method == http.MethodPost {
+			s.approveBill(w, r)
+			return
+		}
 	case strings.HasPrefix(r.URL.Path, "/bills/") && strings.HasSuffix(r.URL.Path, "/paid"):
 		if r.Method == http.MethodPost || r.Method == http.MethodPatch {
 			s.markDebtPaid(w, r)
 			return
 		}
+	case strings.HasPrefix(r.URL.Path, "/bills/") && strings.Count(r.URL.Path, "/") == 2:
+		if r.Method == http.MethodPatch {
+			s.correctBill(w, r)
+			return
+		}
+	case r.URL.Path == "/billers":
+		s.billers(w, r)
+		return
+	case strings.HasPrefix(r.URL.Path, "/billers/"):
+		s.biller(w, r)
+		return
+	case r.URL.Path == "/reports/billers":
+		if r.Method == http.MethodGet {
+			s.billerReport(w, r)
+			return
+		}
+	case r.URL.Path == "/reports/usage":
+		if r.Method == http.MethodGet {
+			s.usageReport(w, r)
+			return
+		}
 	}
 	http.NotFound(w, r)
 }
@@ -68,7 +116,7 @@ type PubSubPushMessage struct {
 	Message struct {
 		Data       []byte            `json:"data"`
 		MessageID  string            `json:"messageId"`
-		Attributes map[string]string  `json:"attributes,omitempty"`
+		Attributes map[string]string `json:"attributes,omitempty"`
 	} `json:"message"`
 	Subscription string `json:"subscription"`
 }
@@ -142,60 +190,293 @@ func (s *Server) processMessage(ctx context.Context, messageID string) error {
 		return err
 	}
 
-	if !filter.Match(&s.cfg.Filters, msg) {
-		return nil
+	// A bill forwarded by a roommate is filtered and extracted as the
+	// original message from the biller. Anyone else's forward is taken as
+	// is, or they could pass off any text as a biller's bill.
+	forwardedBy := ""
+	by, trusted, err := s.forwarder(ctx, gmail.Header(msg, "From"))
+	if err != nil {
+		return err
 	}
-
-	html, plain := gmail.GetMessageBody(msg)
-	extracted, ok := s.extract.Extract(msg, html, plain)
-	if !ok {
-		return nil
+	fetch := func(a gmail.Attachment) ([]byte, error) { return s.gmail.AttachmentData(ctx, messageID, a) }
+	if trusted {
+		if fwd, ok := gmail.Unforward(msg, fetch); ok {
+			msg, forwardedBy = fwd.Original, by
+			log.Printf("message %s: forwarded by %s", messageID, forwardedBy)
+		}
 	}
 
-	roommates, err := s.store.ListActiveRoommates(ctx)
+	billers, err := s.store.ListBillers(ctx)
 	if err != nil {
 		return err
 	}
-	if len(roommates) == 0 {
+	biller := filter.LookupBiller(billers, gmail.Header(msg, "From"))
+
+	// Payment confirmations rarely carry the filter's bill keywords, so
+	// mail from a directory biller is let through to be classified.
+	matched := filter.Match(&s.cfg.Filters, msg)
+	if !matched && biller == nil {
 		return nil
 	}
 
-	debts := split.Split(extracted.TotalAmount, roommates)
+	content := gmail.GetMessageContent(msg)
+	html, plain := content.HTML, content.Plain
+	src := &bill.Source{Message: msg, HTML: html, Plain: plain}
+	if biller != nil {
+		src.Biller = biller.Name
+	}
+	for _, a := range content.Attachments {
+		if !bill.CanParse(a.MimeType, a.Filename) {
+			continue
+		}
+		data, err := s.gmail.AttachmentData(ctx, messageID, a)
+		if err != nil {
+			log.Printf("attachment %s of %s: %v", a.Filename, messageID, err)
+			continue
+		}
+		src.Attachments = append(src.Attachments, bill.Attachment{Filename: a.Filename, MimeType: a.MimeType, Data: data})
+	}
+
+	kind := bill.Classify(src)
+	if kind == bill.KindPayment {
+		return s.recordBillerPayment(ctx, messageID, src, biller)
+	}
+	if !matched {
+		return nil
+	}
+	extracted, ok := s.extract.Extract(src)
+	if !ok {
+		return nil
+	}
+
+	excerpt := plain
+	if excerpt == "" {
+		excerpt = bill.HTMLToText(html)
+	}
+	if len(excerpt) > 2000 {
+		excerpt = excerpt[:2000] + "..."
+	}
 
 	billDoc := &store.Bill{
-		BillerCompany:   extracted.BillerCompany,
+		BillerCompany:  extracted.BillerCompany,
 		TotalAmount:    extracted.TotalAmount,
 		Status:         store.BillStatusUnpaid,
 		DueDate:        extracted.DueDate,
 		DateReceived:   time.Now(),
 		GmailMessageID: messageID,
-		Currency:       "USD",
-		CreatedAt:     time.Now(),
+		CreatedAt:      time.Now(),
+		Confidence:     extracted.Confidence,
+		Excerpt:        excerpt,
+		AccountNumber:  bill.MaskAccount(extracted.AccountNumber),
+		ServiceAddress: extracted.ServiceAddress,
+		BillingPeriod:  extracted.BillingPeriod,
+		ForwardedBy:    forwardedBy,
+		PeriodStart:    extracted.PeriodStart,
+		PeriodEnd:      extracted.PeriodEnd,
+		Kind:           string(kind),
+	}
+	billDoc.Household = filter.Household(s.cfg.Households, billDoc.AccountNumber, billDoc.ServiceAddress)
+	if biller != nil {
+		billDoc.BillerID = biller.ID
+		billDoc.Category = biller.Category
+	}
+	for _, li := range extracted.LineItems {
+		billDoc.LineItems = append(billDoc.LineItems, store.LineItem{Description: li.Description, Amount: li.Amount})
+	}
+	for _, u := range extracted.Usage {
+		billDoc.Usage = append(billDoc.Usage, store.Usage{Quantity: u.Quantity, Unit: u.Unit})
+	}
+	for _, ev := range extracted.Evidence {
+		billDoc.Evidence = append(billDoc.Evidence, store.Evidence{Field: ev.Field, Text: ev.Text, Source: ev.Source})
 	}
 
-	if err := s.store.SaveBill(ctx, billDoc, debts); err != nil {
+	history, err := s.store.ListBillsSince(ctx, time.Now().AddDate(-1, 0, 0))
+	if err != nil {
 		return err
 	}
+	recent := receivedSince(history, time.Now().AddDate(0, -relatedMonths, 0))
+	if existing := relatedBill(recent, billDoc, kind); existing != nil {
+		if kind != bill.KindCorrection {
+			log.Printf("message %s: %s of bill %s", messageID, kind, existing.ID)
+			return s.store.AddRelatedMessage(ctx, existing.ID, messageID)
+		}
+		billDoc.Supersedes = existing.ID
+	}
 
-	dueStr := ""
-	if !extracted.DueDate.IsZero() {
-		dueStr = extracted.DueDate.Format("2006-01-02")
+	if extracted.Confidence < s.cfg.Review.MinConfidence {
+		billDoc.ReviewReasons = append(billDoc.ReviewReasons,
+			fmt.Sprintf("extraction confidence %.2f is below %.2f", extracted.Confidence, s.cfg.Review.MinConfidence))
 	}
-	excerpt := plain
-	if excerpt == "" {
-		excerpt = html
+	problems := validate.Check(s.cfg.Validation, billDoc, billerHistory(history, billDoc))
+	billDoc.ReviewReasons = append(billDoc.ReviewReasons, problems...)
+	if len(billDoc.ReviewReasons) > 0 {
+		billDoc.Status = store.BillStatusNeedsReview
+		log.Printf("message %s: holding for review: %s", messageID, strings.Join(billDoc.ReviewReasons, "; "))
+		if err := s.store.SaveBill(ctx, billDoc, nil); err != nil {
+			return err
+		}
+		if len(problems) > 0 && s.cfg.Validation.AdminEmail != "" {
+			if err := s.notify.SendAdminAlert(ctx, s.cfg.Validation.AdminEmail, billDoc); err != nil {
+				log.Printf("message %s: admin alert: %v", messageID, err)
+			}
+		}
+		return nil
 	}
-	if len(excerpt) > 2000 {
-		excerpt = excerpt[:2000] + "..."
+
+	if err := s.splitAndNotify(ctx, billDoc); err != nil && !errors.Is(err, errNoDebtors) {
+		return err
+	}
+	return nil
+}
+
+// errNoDebtors is returned by splitAndNotify when nobody takes part in a bill
+// and it has been held for review instead.
+var errNoDebtors = errors.New("bill held for review")
+
+// splitAndNotify splits the bill among the roommates of its household (all
+// roommates if it has none) who lived there during its service period and
+// whom its split rule and their opt-outs leave in, saves it with its debts
+// and emails each roommate their share. Shares are prorated by days present.
+// If no roommate takes part, the bill is saved as needs_review with the reason
+// and errNoDebtors is returned.
+func (s *Server) splitAndNotify(ctx context.Context, billDoc *store.Bill) error {
+	all, err := s.store.ListRoommates(ctx)
+	if err != nil {
+		return err
+	}
+	start, end := split.Period(billDoc)
+	rule := split.MatchRule(s.splitRules, billDoc)
+	household := inHousehold(split.Present(all, start, end), billDoc.Household)
+	roommates := split.Eligible(rule, billDoc, household)
+	var previous []store.Debt
+	if billDoc.Supersedes != "" {
+		// A corrected statement is split among the original debtors.
+		if previous, err = s.store.ListDebts(ctx, billDoc.Supersedes); err != nil {
+			return err
+		}
+		if len(previous) > 0 {
+			roommates = debtors(all, previous)
+		}
+	}
+	if len(roommates) == 0 {
+		reason := "no roommate takes part in this bill"
+		switch {
+		case len(household) == 0 && billDoc.Household != "":
+			reason = fmt.Sprintf("no roommate of household %s lived there during the bill period", billDoc.Household)
+		case len(household) == 0:
+			reason = "no roommate lived there during the bill period"
+		}
+		return s.holdNoDebtors(ctx, billDoc, reason)
 	}
 
-		for i, d := range debts {
-			_ = s.notify.SendBillNotification(ctx, roommates[i], billDoc.BillerCompany, d.Amount, dueStr, excerpt)
+	split.AssignItems(s.itemRules, billDoc.BillerCompany, billDoc.LineItems, roommates)
+	parts := split.Participants(roommates, billDoc.BillerID, billDoc.BillerCompany)
+	rule.Apply(parts, roommates)
+	split.Prorate(parts, roommates, start, end)
+	debts := split.ByItems(billDoc.TotalAmount, billDoc.LineItems, parts, billDoc.GmailMessageID)
+	billDoc.Status = carryPayments(debts, previous)
+
+	if err := s.store.SaveBill(ctx, billDoc, debts); err != nil {
+		return err
+	}
+	if billDoc.Supersedes != "" {
+		if err := s.store.SupersedeBill(ctx, billDoc.Supersedes, billDoc.ID); err != nil {
+			return err
 		}
+	}
+
+	notice := notify.Notice{Bill: billDoc}
+	if !billDoc.DueDate.IsZero() {
+		notice.DueDate = billDoc.DueDate.In(s.cfg.Location).Format("2006-01-02")
+	}
+	if billDoc.BillerID != "" {
+		if b, err := s.store.GetBiller(ctx, billDoc.BillerID); err == nil {
+			notice.PayTo = roommateName(all, b.PayerRoommateID)
+			notice.Autopay = b.Autopay
+		}
+	}
+
+	for i, d := range debts {
+		if roommates[i].Email == "" {
+			continue // original debtor no longer on file
+		}
+		notice.Share = d.Amount
+		notice.Paid = d.PaidAmount
+		_ = s.notify.SendBillNotification(ctx, roommates[i], notice)
+	}
 
 	return nil
 }
 
+// holdNoDebtors saves a bill nobody takes part in as needs_review with reason
+// and returns errNoDebtors wrapped with the reason.
+func (s *Server) holdNoDebtors(ctx context.Context, billDoc *store.Bill, reason string) error {
+	billDoc.Status = store.BillStatusNeedsReview
+	if !slices.Contains(billDoc.ReviewReasons, reason) {
+		billDoc.ReviewReasons = append(billDoc.ReviewReasons, reason)
+	}
+	log.Printf("message %s: holding for review: %s", billDoc.GmailMessageID, reason)
+	if err := s.store.SaveBill(ctx, billDoc, nil); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", errNoDebtors, reason)
+}
+
+// forwarder reports whether from may forward bills. It returns the ID of the
+// active roommate whose address is in from, or the address itself if it is
+// listed in filters.forwarders.
+func (s *Server) forwarder(ctx context.Context, from string) (string, bool, error) {
+	addr := from
+	if a, err := mail.ParseAddress(from); err == nil {
+		addr = a.Address
+	}
+	if addr == "" {
+		return "", false, nil
+	}
+	roommates, err := s.store.ListActiveRoommates(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	for _, r := range roommates {
+		if strings.EqualFold(r.Email, addr) {
+			return r.ID, true, nil
+		}
+	}
+	for _, f := range s.cfg.Filters.Forwarders {
+		if strings.EqualFold(f, addr) {
+			return addr, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// roommateName returns the display name (or email) of the roommate with id, or "".
+func roommateName(roommates []store.Roommate, id string) string {
+	for _, r := range roommates {
+		if r.ID == id {
+			if r.DisplayName != "" {
+				return r.DisplayName
+			}
+			return r.Email
+		}
+	}
+	return ""
+}
+
+// inHousehold returns the roommates belonging to household, or all of them if household is empty.
+func inHousehold(roommates []store.Roommate, household string) []store.Roommate {
+	if household == "" {
+		return roommates
+	}
+	var out []store.Roommate
+	for _, r := range roommates {
+		if r.Household == household {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
 // markDebtPaid handles POST/PATCH /bills/{billId}/debts/{roommateId}/paid
 func (s *Server) markDebtPaid(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/bills/")
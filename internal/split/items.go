@@ -0,0 +1,117 @@
+package split
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/akksell/rbn/internal/config"
+	"github.com/akksell/rbn/internal/money"
+	"github.com/akksell/rbn/internal/store"
+)
+
+// ItemRule is a compiled config.LineItemRule.
+type ItemRule struct {
+	Biller      string
+	Description *regexp.Regexp
+	Roommates   []string // IDs or emails
+}
+
+// CompileItemRules compiles the line item rules from config.
+func CompileItemRules(specs []config.LineItemRule) ([]ItemRule, error) {
+	out := make([]ItemRule, 0, len(specs))
+	for i, spec := range specs {
+		re, err := regexp.Compile(spec.Description)
+		if err != nil {
+			return nil, fmt.Errorf("line item rule %d: %w", i+1, err)
+		}
+		if len(spec.Roommates) == 0 {
+			return nil, fmt.Errorf("line item rule %d: no roommates", i+1)
+		}
+		out = append(out, ItemRule{Biller: spec.Biller, Description: re, Roommates: spec.Roommates})
+	}
+	return out, nil
+}
+
+// AssignItems sets RoommateIDs on each line item matched by a rule. Rule
+// roommates that are not among roommates are ignored; an item whose rule
+// names none of them stays shared by everyone.
+func AssignItems(rules []ItemRule, biller string, items []store.LineItem, roommates []store.Roommate) {
+	for i := range items {
+		items[i].RoommateIDs = nil
+		for _, rule := range rules {
+			if rule.Biller != "" && !strings.EqualFold(rule.Biller, biller) {
+				continue
+			}
+			if !rule.Description.MatchString(items[i].Description) {
+				continue
+			}
+			for _, r := range roommates {
+				if named(rule.Roommates, r) {
+					items[i].RoommateIDs = append(items[i].RoommateIDs, r.ID)
+				}
+			}
+			break
+		}
+	}
+}
+
+func named(names []string, r store.Roommate) bool {
+	for _, n := range names {
+		if n == r.ID || (r.Email != "" && strings.EqualFold(n, r.Email)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ByItems splits each line item among the participants it is assigned to and
+// the rest of totalAmount (taxes, credits and anything not itemized) among all
+// participants, each by their share. Items in another currency than the total
+// are treated as part of the rest. Fixed amounts, caps and floors apply to
+// each participant's share of the whole bill. Debts are in parts order and
+// add up exactly to totalAmount; seed is passed on to Allocate.
+func ByItems(totalAmount money.Money, items []store.LineItem, parts []Participant, seed string) []store.Debt {
+	if len(parts) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(parts))
+	for i, p := range parts {
+		index[p.RoommateID] = i
+	}
+	ideal := make([]float64, len(parts))
+	add := func(amount money.Money, sharers []Participant) {
+		for k, v := range idealShares(float64(amount.Minor), sharers) {
+			ideal[index[sharers[k].RoommateID]] += v
+		}
+	}
+
+	rest := totalAmount
+	for _, item := range items {
+		if item.Amount.Currency != totalAmount.Currency {
+			continue
+		}
+		add(item.Amount, sharedBy(item.RoommateIDs, parts))
+		rest = rest.Sub(item.Amount)
+	}
+	add(rest, parts)
+	return settle(totalAmount, parts, ideal, seed)
+}
+
+// sharedBy returns the participants with the given IDs, or all of them if
+// ids is empty or names none of them.
+func sharedBy(ids []string, parts []Participant) []Participant {
+	var out []Participant
+	for _, p := range parts {
+		for _, id := range ids {
+			if p.RoommateID == id {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	if len(out) == 0 {
+		return parts
+	}
+	return out
+}
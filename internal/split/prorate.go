@@ -0,0 +1,96 @@
+package split
+
+import (
+	"time"
+
+	"github.com/akksell/rbn/internal/store"
+)
+
+// Period returns the days a bill covers: its service period, or the day it
+// was received if no period was extracted.
+func Period(b *store.Bill) (start, end time.Time) {
+	if !b.PeriodStart.IsZero() && !b.PeriodEnd.IsZero() && !b.PeriodEnd.Before(b.PeriodStart) {
+		return b.PeriodStart, b.PeriodEnd
+	}
+	return b.DateReceived, b.DateReceived
+}
+
+// stay is the part of a bill's period a participant lived there, as day
+// offsets from the start of the period, inclusive.
+type stay struct {
+	from, to int
+	days     int // days in the period
+}
+
+func (st *stay) covers(d int) bool { return st == nil || (st.from <= d && d <= st.to) }
+
+// stayIn returns the days from start to end, inclusive, the roommate lived
+// there. ok is false if they lived there on none of them.
+func stayIn(r store.Roommate, start, end time.Time) (st stay, ok bool) {
+	loc := start.Location()
+	first, last := day(start, loc), day(end, loc)
+	st = stay{from: 0, to: days(first, last) - 1, days: days(first, last)}
+	if r.MoveIn != nil {
+		if in := day(*r.MoveIn, loc); in.After(first) {
+			st.from = days(first, in) - 1
+		}
+	}
+	if r.MoveOut != nil {
+		if out := day(*r.MoveOut, loc); out.Before(last) {
+			st.to = days(first, out) - 1
+		}
+	} else if !r.Active {
+		return st, false // moved out, date unknown
+	}
+	return st, st.from <= st.to
+}
+
+// DaysPresent returns how many of the days from start to end, inclusive, the
+// roommate lived there, and the number of days in the period.
+func DaysPresent(r store.Roommate, start, end time.Time) (present, total int) {
+	st, ok := stayIn(r, start, end)
+	if !ok {
+		return 0, st.days
+	}
+	return st.to - st.from + 1, st.days
+}
+
+// Present returns the roommates who lived there on at least one day from
+// start to end, including those who have since moved out.
+func Present(roommates []store.Roommate, start, end time.Time) []store.Roommate {
+	var out []store.Roommate
+	for _, r := range roommates {
+		if n, _ := DaysPresent(r, start, end); n > 0 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Prorate limits each participant to the days from start to end its roommate
+// lived there: each day's part of the bill is then shared among the roommates
+// there that day, by their weights and percentages. Fixed amounts, caps and
+// floors are scaled by the fraction of the days present. parts and roommates
+// are in the same order.
+func Prorate(parts []Participant, roommates []store.Roommate, start, end time.Time) {
+	for i, r := range roommates {
+		st, ok := stayIn(r, start, end)
+		if !ok || st.to-st.from+1 == st.days {
+			continue // !ok: an original debtor no longer on file
+		}
+		parts[i].stay = &st
+		f := float64(st.to-st.from+1) / float64(st.days)
+		parts[i].Fixed *= f
+		parts[i].Cap *= f
+		parts[i].Floor *= f
+	}
+}
+
+func day(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func days(from, to time.Time) int {
+	return int(to.Sub(from).Hours()/24) + 1
+}
@@ -0,0 +1,113 @@
+package split
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/akksell/rbn/internal/config"
+	"github.com/akksell/rbn/internal/store"
+)
+
+// Rule is a compiled config.SplitRule.
+type Rule struct {
+	Biller    string
+	Category  string
+	Roommates []string // IDs or emails; empty means everyone
+	Exclude   []string
+	Shares    map[string]store.Share // by roommate ID or email
+}
+
+// CompileRules checks the split rules from config.
+func CompileRules(specs []config.SplitRule) ([]Rule, error) {
+	out := make([]Rule, 0, len(specs))
+	for i, spec := range specs {
+		if spec.Biller == "" && spec.Category == "" {
+			return nil, fmt.Errorf("split rule %d: biller or category is required", i+1)
+		}
+		rule := Rule{Biller: spec.Biller, Category: spec.Category, Roommates: spec.Roommates, Exclude: spec.Exclude}
+		for name, s := range spec.Shares {
+			if s.Weight < 0 || s.Percent < 0 || s.Percent > 100 || s.Fixed < 0 || s.Cap < 0 || s.Floor < 0 {
+				return nil, fmt.Errorf("split rule %d: invalid share for %s", i+1, name)
+			}
+			if s.Cap > 0 && s.Floor > s.Cap {
+				return nil, fmt.Errorf("split rule %d: floor above cap for %s", i+1, name)
+			}
+			if rule.Shares == nil {
+				rule.Shares = make(map[string]store.Share, len(spec.Shares))
+			}
+			rule.Shares[name] = store.Share{Weight: s.Weight, Percent: s.Percent, Fixed: s.Fixed, Cap: s.Cap, Floor: s.Floor}
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+// MatchRule returns the first rule for the bill's biller or category, or nil.
+func MatchRule(rules []Rule, b *store.Bill) *Rule {
+	for i, rule := range rules {
+		if rule.Biller != "" {
+			if strings.EqualFold(rule.Biller, b.BillerCompany) || strings.EqualFold(rule.Biller, b.BillerID) {
+				return &rules[i]
+			}
+			continue
+		}
+		if strings.EqualFold(rule.Category, b.Category) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// Eligible returns the roommates that take part in the bill: those who have
+// not opted out of its biller or category and, if rule is not nil, are
+// included and not excluded by it.
+func Eligible(rule *Rule, b *store.Bill, roommates []store.Roommate) []store.Roommate {
+	var out []store.Roommate
+	for _, r := range roommates {
+		if optedOut(r, b) {
+			continue
+		}
+		if rule != nil {
+			if len(rule.Roommates) > 0 && !named(rule.Roommates, r) {
+				continue
+			}
+			if named(rule.Exclude, r) {
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func optedOut(r store.Roommate, b *store.Bill) bool {
+	for _, o := range r.OptOut {
+		if o == "" {
+			continue
+		}
+		if strings.EqualFold(o, b.BillerID) || strings.EqualFold(o, b.BillerCompany) || strings.EqualFold(o, b.Category) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply sets the share of each participant the rule names. parts and
+// roommates are in the same order. A nil rule changes nothing.
+func (rule *Rule) Apply(parts []Participant, roommates []store.Roommate) {
+	if rule == nil {
+		return
+	}
+	for i, r := range roommates {
+		if s, ok := rule.Shares[r.ID]; ok {
+			parts[i].Share = s
+			continue
+		}
+		for name, s := range rule.Shares {
+			if r.Email != "" && strings.EqualFold(name, r.Email) {
+				parts[i].Share = s
+				break
+			}
+		}
+	}
+}
@@ -1,35 +1,321 @@
 package split
 
 import (
+	"hash/fnv"
 	"math"
+	"sort"
 
+	"github.com/akksell/rbn/internal/money"
 	"github.com/akksell/rbn/internal/store"
 )
 
-// Split divides totalAmount equally among roommates and returns one Debt per roommate.
-func Split(totalAmount float64, roommates []store.Roommate) []store.Debt {
-	if len(roommates) == 0 {
-		return nil
+// Participant is a roommate taking part in a split.
+type Participant struct {
+	RoommateID string
+	store.Share
+
+	stay *stay // days of the bill's period they lived there, set by Prorate; nil means all
+}
+
+// Participants returns a participant per roommate, in order. Each roommate's
+// share comes from the first of keys (biller ID, biller name) found in their
+// BillerShares, else from their own Weight and Percent.
+func Participants(roommates []store.Roommate, keys ...string) []Participant {
+	parts := make([]Participant, len(roommates))
+	for i, r := range roommates {
+		parts[i] = Participant{RoommateID: r.ID, Share: store.Share{Weight: r.Weight, Percent: r.Percent}}
+		for _, k := range keys {
+			if s, ok := r.BillerShares[k]; ok && k != "" {
+				parts[i].Share = s
+				break
+			}
+		}
 	}
-	share := totalAmount / float64(len(roommates))
-	// Round to 2 decimal places to avoid floating point noise
-	share = math.Round(share*100) / 100
+	return parts
+}
 
-	debts := make([]store.Debt, len(roommates))
+// Split divides totalAmount equally among roommates and returns one Debt per
+// roommate. The debts always add up exactly to totalAmount; seed decides who
+// absorbs the remainder, see Allocate.
+func Split(totalAmount money.Money, roommates []store.Roommate, seed string) []store.Debt {
+	parts := make([]Participant, len(roommates))
 	for i, r := range roommates {
+		parts[i] = Participant{RoommateID: r.ID}
+	}
+	return Allocate(totalAmount, parts, seed)
+}
+
+// Allocate divides totalAmount among parts by their shares and returns one
+// Debt per participant, in order. The debts always add up exactly to
+// totalAmount: each share is rounded down to the minor unit and the leftover
+// units go one each to the shares rounded down the most (largest remainder).
+// Ties are broken by a rotation derived from seed, usually the bill ID, so
+// the same roommate does not absorb every extra cent. Each debt's Rounding
+// records the units it absorbed.
+func Allocate(totalAmount money.Money, parts []Participant, seed string) []store.Debt {
+	if len(parts) == 0 {
+		return nil
+	}
+	return settle(totalAmount, parts, idealShares(float64(totalAmount.Minor), parts), seed)
+}
+
+// settle applies fixed amounts, caps and floors to the exact shares in ideal
+// and rounds them to debts that add up to totalAmount. Credits (totals of
+// zero or less) are shared by percentage and weight only.
+func settle(totalAmount money.Money, parts []Participant, ideal []float64, seed string) []store.Debt {
+	if totalAmount.Minor > 0 {
+		constrain(float64(totalAmount.Minor), parts, ideal, totalAmount.Currency)
+	}
+
+	debts := make([]store.Debt, len(parts))
+	frac := make([]float64, len(parts))
+	remainder := totalAmount.Minor
+	for i, p := range parts {
+		minor := int64(math.Floor(ideal[i]))
+		frac[i] = ideal[i] - float64(minor)
+		remainder -= minor
 		debts[i] = store.Debt{
-			RoommateID: r.ID,
-			Amount:     share,
+			RoommateID: p.RoommateID,
+			Amount:     money.New(minor, totalAmount.Currency),
 			Status:     store.DebtStatusPending,
+			Rounding:   money.New(0, totalAmount.Currency),
 		}
 	}
 
-	// Adjust first debt for rounding so total matches
-	sum := share * float64(len(roommates))
-	diff := totalAmount - sum
-	if diff != 0 {
-		debts[0].Amount = math.Round((debts[0].Amount+diff)*100) / 100
+	order := remainderOrder(frac, seed)
+	for k := int64(0); k < remainder; k++ {
+		d := &debts[order[k%int64(len(order))]]
+		d.Amount.Minor++
+		d.Rounding.Minor++
 	}
-
 	return debts
 }
+
+// remainderOrder returns participant indexes by descending fractional share.
+// Equal fractions (within float error) are ordered by a rotation of the
+// indexes starting at a position derived from seed.
+func remainderOrder(frac []float64, seed string) []int {
+	n := len(frac)
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	start := int(h.Sum32() % uint32(n))
+	rank := func(i int) int { return (i - start + n) % n }
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		fa, fb := frac[order[a]], frac[order[b]]
+		if math.Abs(fa-fb) > 1e-9 {
+			return fa > fb
+		}
+		return rank(order[a]) < rank(order[b])
+	})
+	return order
+}
+
+// constrain adjusts ideal, which adds up to total, so that participants with
+// a fixed amount pay exactly that and the others pay within their cap and
+// floor, while still adding up to total. What a fixed amount, cap or floor
+// takes from or adds to a share is spread over the remaining participants in
+// proportion to their shares. No share goes below zero: fixed amounts are
+// scaled down if they exceed total, and floors are scaled down if they exceed
+// what is left after the fixed amounts, leaving the others at zero. If every
+// participant ends up at a cap, the difference is spread over them anyway,
+// since the total must be paid.
+func constrain(total float64, parts []Participant, ideal []float64, currency string) {
+	minor := func(major float64) float64 { return float64(money.FromFloat(major, currency).Minor) }
+
+	var fixed, flex []int
+	var fixedSum float64
+	for i, p := range parts {
+		if p.Fixed > 0 {
+			ideal[i] = minor(p.Fixed)
+			fixedSum += ideal[i]
+			fixed = append(fixed, i)
+		} else {
+			flex = append(flex, i)
+		}
+	}
+	if fixedSum > total {
+		for _, i := range fixed {
+			ideal[i] *= total / fixedSum
+		}
+		fixedSum = total
+	}
+	if len(flex) == 0 {
+		fill(ideal, fixed, total, parts)
+		return
+	}
+
+	clamped := make([]bool, len(parts))
+	for {
+		rest := total - fixedSum
+		var free, held []int
+		for _, i := range flex {
+			if clamped[i] {
+				rest -= ideal[i]
+				held = append(held, i)
+			} else {
+				free = append(free, i)
+			}
+		}
+		if rest <= 0 && len(held) > 0 {
+			for _, i := range free {
+				ideal[i] = 0
+			}
+			fill(ideal, held, total-fixedSum, parts)
+			return
+		}
+		if len(free) == 0 {
+			fill(ideal, flex, total-fixedSum, parts)
+			return
+		}
+		fill(ideal, free, rest, parts)
+
+		changed := false
+		for _, i := range free {
+			p := parts[i]
+			switch {
+			case p.Cap > 0 && ideal[i] > minor(p.Cap):
+				ideal[i] = minor(p.Cap)
+			case p.Floor > 0 && ideal[i] < minor(p.Floor):
+				ideal[i] = minor(p.Floor)
+			default:
+				continue
+			}
+			clamped[i] = true
+			changed = true
+		}
+		if !changed {
+			return
+		}
+	}
+}
+
+// fill resizes the shares in ideal of the participants at idx to add up to
+// amount, keeping their proportions, or by percentage and weight if their
+// shares are all zero.
+func fill(ideal []float64, idx []int, amount float64, parts []Participant) {
+	var sum float64
+	for _, i := range idx {
+		sum += ideal[i]
+	}
+	if sum > 0 {
+		for _, i := range idx {
+			ideal[i] = amount * ideal[i] / sum
+		}
+		return
+	}
+	sub := make([]Participant, len(idx))
+	for k, i := range idx {
+		sub[k] = parts[i]
+	}
+	for k, v := range idealShares(amount, sub) {
+		ideal[idx[k]] = v
+	}
+}
+
+// idealShares returns each participant's exact share of total in minor
+// units. If some participants lived there for only part of the bill's period
+// (see Prorate), total is spread evenly over the days of the period and each
+// run of days with the same participants present is divided among them.
+// Days nobody was there are divided among everyone.
+func idealShares(total float64, parts []Participant) []float64 {
+	n := 0
+	for _, p := range parts {
+		if p.stay != nil {
+			n = p.stay.days
+			break
+		}
+	}
+	if n == 0 {
+		return byShare(total, parts)
+	}
+
+	cuts := []int{0, n}
+	for _, p := range parts {
+		if p.stay != nil {
+			cuts = append(cuts, p.stay.from, p.stay.to+1)
+		}
+	}
+	sort.Ints(cuts)
+
+	out := make([]float64, len(parts))
+	var unclaimed float64
+	for k := 0; k+1 < len(cuts); k++ {
+		a, b := cuts[k], cuts[k+1]
+		if a == b {
+			continue
+		}
+		amount := total * float64(b-a) / float64(n)
+		var idx []int
+		for i, p := range parts {
+			if p.stay.covers(a) {
+				idx = append(idx, i)
+			}
+		}
+		if len(idx) == 0 {
+			unclaimed += amount
+			continue
+		}
+		sub := make([]Participant, len(idx))
+		for j, i := range idx {
+			sub[j] = parts[i]
+		}
+		for j, v := range byShare(amount, sub) {
+			out[idx[j]] += v
+		}
+	}
+	if unclaimed != 0 {
+		for i, v := range byShare(unclaimed, parts) {
+			out[i] += v
+		}
+	}
+	return out
+}
+
+// byShare divides total among parts: percentages come off the top (scaled
+// down if they exceed 100) and the rest is divided by weight. If every
+// participant has a percentage, the rest is divided in proportion to the
+// percentages.
+func byShare(total float64, parts []Participant) []float64 {
+	var percent, weight float64
+	for _, p := range parts {
+		if p.Percent > 0 {
+			percent += p.Percent
+		} else {
+			weight += weightOf(p)
+		}
+	}
+	scale := 1.0
+	if percent > 100 {
+		scale = 100 / percent
+	}
+
+	out := make([]float64, len(parts))
+	rest := total
+	for i, p := range parts {
+		if p.Percent > 0 {
+			out[i] = total * p.Percent * scale / 100
+			rest -= out[i]
+		}
+	}
+	for i, p := range parts {
+		switch {
+		case weight > 0 && p.Percent <= 0:
+			out[i] = rest * weightOf(p) / weight
+		case weight == 0 && percent > 0:
+			out[i] += rest * p.Percent / percent
+		}
+	}
+	return out
+}
+
+func weightOf(p Participant) float64 {
+	if p.Weight <= 0 {
+		return 1
+	}
+	return p.Weight
+}
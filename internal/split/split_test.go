@@ -0,0 +1,397 @@
+package split_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/akksell/rbn/internal/config"
+	"github.com/akksell/rbn/internal/money"
+	"github.com/akksell/rbn/internal/split"
+	"github.com/akksell/rbn/internal/store"
+)
+
+// amounts returns the debt amounts in minor units, failing t if any is
+// negative or they do not add up to total.
+func amounts(t *testing.T, total money.Money, debts []store.Debt) []int64 {
+	t.Helper()
+	out := make([]int64, len(debts))
+	var sum int64
+	for i, d := range debts {
+		if d.Amount.Minor < 0 {
+			t.Errorf("debt %s is negative: %d", d.RoommateID, d.Amount.Minor)
+		}
+		if d.Amount.Currency != total.Currency {
+			t.Errorf("debt %s currency = %s, want %s", d.RoommateID, d.Amount.Currency, total.Currency)
+		}
+		out[i] = d.Amount.Minor
+		sum += d.Amount.Minor
+	}
+	if sum != total.Minor {
+		t.Errorf("debts add up to %d, want %d", sum, total.Minor)
+	}
+	return out
+}
+
+func part(id string, s store.Share) split.Participant {
+	return split.Participant{RoommateID: id, Share: s}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int64
+		parts []split.Participant
+		want  []int64
+	}{
+		{"equal", 9000, []split.Participant{part("a", store.Share{}), part("b", store.Share{}), part("c", store.Share{})}, []int64{3000, 3000, 3000}},
+		{"weights", 9000, []split.Participant{part("a", store.Share{Weight: 2}), part("b", store.Share{}), part("c", store.Share{Weight: 1})}, []int64{4500, 2250, 2250}},
+		{"percent off the top", 10000, []split.Participant{part("a", store.Share{Percent: 40}), part("b", store.Share{}), part("c", store.Share{})}, []int64{4000, 3000, 3000}},
+		{"percent with weights", 10000, []split.Participant{part("a", store.Share{Percent: 40}), part("b", store.Share{Weight: 2}), part("c", store.Share{})}, []int64{4000, 4000, 2000}},
+		{"percentages only", 10000, []split.Participant{part("a", store.Share{Percent: 30}), part("b", store.Share{Percent: 10})}, []int64{7500, 2500}},
+		{"percentages over 100", 10000, []split.Participant{part("a", store.Share{Percent: 150}), part("b", store.Share{Percent: 50}), part("c", store.Share{})}, []int64{7500, 2500, 0}},
+		{"credit", -9000, []split.Participant{part("a", store.Share{Weight: 2}), part("b", store.Share{})}, []int64{-6000, -3000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total := money.New(tt.total, "USD")
+			debts := split.Allocate(total, tt.parts, "bill")
+			var got []int64
+			var sum int64
+			for _, d := range debts {
+				got = append(got, d.Amount.Minor)
+				sum += d.Amount.Minor
+			}
+			if sum != tt.total {
+				t.Errorf("debts add up to %d, want %d", sum, tt.total)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocateNoParticipants(t *testing.T) {
+	if debts := split.Allocate(money.New(100, "USD"), nil, "bill"); debts != nil {
+		t.Errorf("got %v, want nil", debts)
+	}
+}
+
+func TestParticipants(t *testing.T) {
+	roommates := []store.Roommate{
+		{ID: "a", Percent: 40, BillerShares: map[string]store.Share{"Metro Fiber": {Weight: 3}}},
+		{ID: "b", Weight: 2},
+		{ID: "c"},
+	}
+	tests := []struct {
+		keys []string
+		want []store.Share
+	}{
+		{nil, []store.Share{{Percent: 40}, {Weight: 2}, {}}},
+		{[]string{"biller-1", "Metro Fiber"}, []store.Share{{Weight: 3}, {Weight: 2}, {}}},
+		{[]string{"", "City Water"}, []store.Share{{Percent: 40}, {Weight: 2}, {}}},
+	}
+	for _, tt := range tests {
+		parts := split.Participants(roommates, tt.keys...)
+		for i, p := range parts {
+			if p.RoommateID != roommates[i].ID || p.Share != tt.want[i] {
+				t.Errorf("keys %v: participant %d = %+v, want %s %+v", tt.keys, i, p, roommates[i].ID, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestByItems(t *testing.T) {
+	parts := []split.Participant{part("a", store.Share{}), part("b", store.Share{}), part("c", store.Share{})}
+	items := []store.LineItem{
+		{Description: "Premium channels", Amount: money.New(1500, "USD"), RoommateIDs: []string{"a"}},
+		{Description: "Internet", Amount: money.New(6000, "USD")},
+		{Description: "Foreign fee", Amount: money.New(100, "EUR")},
+	}
+	total := money.New(9000, "USD")
+	got := amounts(t, total, split.ByItems(total, items, parts, "bill"))
+	if want := []int64{4000, 2500, 2500}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAllocateRemainder(t *testing.T) {
+	parts := []split.Participant{part("a", store.Share{}), part("b", store.Share{}), part("c", store.Share{})}
+	total := money.New(10001, "USD")
+
+	// Ties rotate with the seed, so over many bills each roommate absorbs
+	// about the same number of extra cents.
+	absorbed := make(map[string]int64)
+	for _, seed := range []string{"m1", "m2", "m3", "m4", "m5", "m6", "m7", "m8", "m9"} {
+		debts := split.Allocate(total, parts, seed)
+		amounts(t, total, debts)
+		var rounding int64
+		for _, d := range debts {
+			if d.Amount.Minor != 3333 && d.Amount.Minor != 3334 {
+				t.Errorf("seed %s: %s owes %d", seed, d.RoommateID, d.Amount.Minor)
+			}
+			if d.Rounding.Minor != d.Amount.Minor-3333 {
+				t.Errorf("seed %s: %s rounding = %d, amount %d", seed, d.RoommateID, d.Rounding.Minor, d.Amount.Minor)
+			}
+			rounding += d.Rounding.Minor
+			absorbed[d.RoommateID] += d.Rounding.Minor
+		}
+		if rounding != 2 {
+			t.Errorf("seed %s: rounding adds up to %d, want 2", seed, rounding)
+		}
+	}
+	for _, p := range parts {
+		if absorbed[p.RoommateID] == 0 || absorbed[p.RoommateID] == 9*2 {
+			t.Errorf("remainders not rotated: %v", absorbed)
+			break
+		}
+	}
+
+	// The same seed always gives the same debts.
+	if a, b := split.Allocate(total, parts, "m1"), split.Allocate(total, parts, "m1"); !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed gave %v and %v", a, b)
+	}
+}
+
+func TestAllocateLargestRemainder(t *testing.T) {
+	// Exact shares 1000.2, 500.1 and 499.7 minor units: the one leftover
+	// unit goes to c, which was rounded down the most, whatever the seed.
+	parts := []split.Participant{part("a", store.Share{Weight: 10002}), part("b", store.Share{Weight: 5001}), part("c", store.Share{Weight: 4997})}
+	total := money.New(2000, "USD")
+	for _, seed := range []string{"x", "y", "z"} {
+		got := amounts(t, total, split.Allocate(total, parts, seed))
+		if want := []int64{1000, 500, 500}; !reflect.DeepEqual(got, want) {
+			t.Errorf("seed %s: got %v, want %v", seed, got, want)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	rules, err := split.CompileRules([]config.SplitRule{
+		{Biller: "Streamflix", Roommates: []string{"a", "b@example.com"}},
+		{Category: "gas", Exclude: []string{"c"}, Shares: map[string]config.ShareSpec{"a@example.com": {Percent: 50}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roommates := []store.Roommate{
+		{ID: "a", Email: "a@example.com"},
+		{ID: "b", Email: "b@example.com"},
+		{ID: "c", Email: "c@example.com"},
+		{ID: "d", Email: "d@example.com", OptOut: []string{"Streamflix", "electric"}},
+	}
+	ids := func(rs []store.Roommate) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		bill store.Bill
+		rule int // index of the matching rule, -1 for none
+		want []string
+	}{
+		{"included by ID or email", store.Bill{BillerCompany: "streamflix"}, 0, []string{"a", "b"}},
+		{"biller ID matches", store.Bill{BillerID: "Streamflix"}, 0, []string{"a", "b"}},
+		{"excluded by category", store.Bill{BillerCompany: "Harbor Gas", Category: "Gas"}, 1, []string{"a", "b", "d"}},
+		{"opted out by category", store.Bill{BillerCompany: "Bay Electric", Category: "electric"}, -1, []string{"a", "b", "c"}},
+		{"no rule", store.Bill{BillerCompany: "City Water", Category: "water"}, -1, []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := split.MatchRule(rules, &tt.bill)
+			switch {
+			case tt.rule < 0 && rule != nil:
+				t.Errorf("matched %+v, want none", *rule)
+			case tt.rule >= 0 && rule != &rules[tt.rule]:
+				t.Errorf("matched %v, want rule %d", rule, tt.rule)
+			}
+			if got := ids(split.Eligible(rule, &tt.bill, roommates)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("eligible %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Rule shares override the roommates' own, matched by email here.
+	gas := &store.Bill{Category: "gas"}
+	rule := split.MatchRule(rules, gas)
+	eligible := split.Eligible(rule, gas, roommates)
+	parts := split.Participants(eligible)
+	rule.Apply(parts, eligible)
+	total := money.New(9000, "USD")
+	if got, want := amounts(t, total, split.Allocate(total, parts, "bill")), []int64{4500, 2250, 2250}; !reflect.DeepEqual(got, want) {
+		t.Errorf("gas split %v, want %v", got, want)
+	}
+
+	// A nil rule changes nothing.
+	var none *split.Rule
+	none.Apply(parts, eligible)
+}
+
+func TestCompileRulesErrors(t *testing.T) {
+	tests := map[string]config.SplitRule{
+		"no biller or category": {Roommates: []string{"a"}},
+		"percent over 100":      {Biller: "x", Shares: map[string]config.ShareSpec{"a": {Percent: 120}}},
+		"negative weight":       {Biller: "x", Shares: map[string]config.ShareSpec{"a": {Weight: -1}}},
+		"negative fixed":        {Biller: "x", Shares: map[string]config.ShareSpec{"a": {Fixed: -5}}},
+		"floor above cap":       {Biller: "x", Shares: map[string]config.ShareSpec{"a": {Cap: 10, Floor: 20}}},
+	}
+	for name, spec := range tests {
+		if _, err := split.CompileRules([]config.SplitRule{spec}); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// on returns noon on the given day of 2026.
+func on(month time.Month, day int) *time.Time {
+	t := time.Date(2026, month, day, 12, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestProrate(t *testing.T) {
+	start, end := *on(time.September, 1), *on(time.September, 30)
+	tests := []struct {
+		name      string
+		roommates []store.Roommate
+		want      []int64
+	}{
+		{"whole period", []store.Roommate{{ID: "a", Active: true}, {ID: "b", Active: true}}, []int64{4500, 4500}},
+		// b pays half of the first 15 days: $22.50 of $90.00.
+		{"move-out", []store.Roommate{{ID: "a", Active: true}, {ID: "b", MoveOut: on(time.September, 15)}}, []int64{6750, 2250}},
+		{"move-in", []store.Roommate{{ID: "a", Active: true}, {ID: "b", Active: true, MoveIn: on(time.September, 16)}}, []int64{6750, 2250}},
+		{"percentages", []store.Roommate{{ID: "a", Active: true, Percent: 50}, {ID: "b", Percent: 50, MoveOut: on(time.September, 15)}}, []int64{6750, 2250}},
+		{"percentage and weights", []store.Roommate{{ID: "a", Active: true, Percent: 40}, {ID: "b", Active: true}, {ID: "c", MoveOut: on(time.September, 15)}}, []int64{3600, 4050, 1350}},
+		// Nobody was there from the 11th to the 20th; those days are shared by both.
+		{"gap", []store.Roommate{{ID: "a", MoveOut: on(time.September, 10)}, {ID: "b", Active: true, MoveIn: on(time.September, 21)}}, []int64{4500, 4500}},
+		// a moves out as b moves in: each pays for their half.
+		{"handover", []store.Roommate{{ID: "a", MoveOut: on(time.September, 15)}, {ID: "b", Active: true, MoveIn: on(time.September, 16)}}, []int64{4500, 4500}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roommates := split.Present(tt.roommates, start, end)
+			parts := split.Participants(roommates)
+			split.Prorate(parts, roommates, start, end)
+			total := money.New(9000, "USD")
+			if got := amounts(t, total, split.Allocate(total, parts, "bill")); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProrateItems(t *testing.T) {
+	// b moved out halfway and only shared the premium channels.
+	start, end := *on(time.September, 1), *on(time.September, 30)
+	roommates := []store.Roommate{{ID: "a", Active: true}, {ID: "b", MoveOut: on(time.September, 15)}}
+	parts := split.Participants(roommates)
+	split.Prorate(parts, roommates, start, end)
+	items := []store.LineItem{{Description: "Premium", Amount: money.New(3000, "USD"), RoommateIDs: []string{"b"}}}
+	total := money.New(9000, "USD")
+	// Premium: b has the first half, the second half falls to nobody and is
+	// shared by its sharer b. The rest: a 4500, b 1500.
+	if got, want := amounts(t, total, split.ByItems(total, items, parts, "bill")), []int64{4500, 4500}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPresent(t *testing.T) {
+	start, end := *on(time.September, 1), *on(time.September, 30)
+	roommates := []store.Roommate{
+		{ID: "here", Active: true},
+		{ID: "left", MoveOut: on(time.September, 1)},
+		{ID: "gone"}, // inactive with no move-out date
+		{ID: "later", Active: true, MoveIn: on(time.October, 1)},
+		{ID: "earlier", MoveOut: on(time.August, 31)},
+	}
+	var got []string
+	for _, r := range split.Present(roommates, start, end) {
+		got = append(got, r.ID)
+	}
+	if want := []string{"here", "left"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("present %v, want %v", got, want)
+	}
+	if present, total := split.DaysPresent(roommates[1], start, end); present != 1 || total != 30 {
+		t.Errorf("days present = %d of %d, want 1 of 30", present, total)
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	received := *on(time.October, 3)
+	b := &store.Bill{DateReceived: received}
+	if start, end := split.Period(b); !start.Equal(received) || !end.Equal(received) {
+		t.Errorf("no period: got %v..%v, want the received date", start, end)
+	}
+	b.PeriodStart, b.PeriodEnd = *on(time.September, 1), *on(time.September, 30)
+	if start, end := split.Period(b); !start.Equal(b.PeriodStart) || !end.Equal(b.PeriodEnd) {
+		t.Errorf("got %v..%v, want the service period", start, end)
+	}
+}
+
+func TestAllocateConstraints(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int64
+		parts []split.Participant
+		want  []int64
+	}{
+		{"fixed", 5000, []split.Participant{part("a", store.Share{Fixed: 50}), part("b", store.Share{}), part("c", store.Share{})}, []int64{5000, 0, 0}},
+		{"fixed and rest", 5500, []split.Participant{part("a", store.Share{Fixed: 50}), part("b", store.Share{}), part("c", store.Share{})}, []int64{5000, 250, 250}},
+		{"fixed over total", 3000, []split.Participant{part("a", store.Share{Fixed: 50}), part("b", store.Share{Fixed: 25}), part("c", store.Share{})}, []int64{2000, 1000, 0}},
+		{"fixed only, under total", 10000, []split.Participant{part("a", store.Share{Fixed: 50}), part("b", store.Share{Fixed: 25})}, []int64{6667, 3333}},
+		{"cap", 9000, []split.Participant{part("a", store.Share{Cap: 20}), part("b", store.Share{}), part("c", store.Share{})}, []int64{2000, 3500, 3500}},
+		{"all capped", 9000, []split.Participant{part("a", store.Share{Cap: 20}), part("b", store.Share{Cap: 20})}, []int64{4500, 4500}},
+		{"fixed and cap", 9000, []split.Participant{part("a", store.Share{Fixed: 30}), part("b", store.Share{Cap: 10}), part("c", store.Share{})}, []int64{3000, 1000, 5000}},
+		{"floor", 6000, []split.Participant{part("a", store.Share{Floor: 40}), part("b", store.Share{Weight: 2}), part("c", store.Share{})}, []int64{4000, 1333, 667}},
+		{"floor over total", 5000, []split.Participant{part("a", store.Share{Floor: 60}), part("b", store.Share{})}, []int64{5000, 0}},
+		{"floors over total", 5000, []split.Participant{part("a", store.Share{Floor: 60}), part("b", store.Share{Floor: 40}), part("c", store.Share{})}, []int64{3000, 2000, 0}},
+		{"fixed and floor over total", 5000, []split.Participant{part("a", store.Share{Fixed: 30}), part("b", store.Share{Floor: 40}), part("c", store.Share{})}, []int64{3000, 2000, 0}},
+		{"cap and floor", 10000, []split.Participant{part("a", store.Share{Cap: 20}), part("b", store.Share{Floor: 50}), part("c", store.Share{})}, []int64{2000, 5000, 3000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total := money.New(tt.total, "USD")
+			if got := amounts(t, total, split.Allocate(total, tt.parts, "bill")); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByItemsCap(t *testing.T) {
+	// The cap holds for a's share of the whole bill, not per item.
+	parts := []split.Participant{part("a", store.Share{Cap: 35}), part("b", store.Share{}), part("c", store.Share{})}
+	items := []store.LineItem{{Description: "Premium", Amount: money.New(3000, "USD"), RoommateIDs: []string{"a"}}}
+	total := money.New(9000, "USD")
+	if got, want := amounts(t, total, split.ByItems(total, items, parts, "bill")), []int64{3500, 2750, 2750}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAllocateCreditIgnoresConstraints(t *testing.T) {
+	parts := []split.Participant{part("a", store.Share{Fixed: 50, Floor: 10}), part("b", store.Share{Cap: 1})}
+	var got []int64
+	for _, d := range split.Allocate(money.New(-1000, "USD"), parts, "bill") {
+		got = append(got, d.Amount.Minor)
+	}
+	if want := []int64{-500, -500}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestProrateFixed(t *testing.T) {
+	// A fixed parking charge is prorated for a roommate who moved in mid-period.
+	start, end := *on(time.September, 1), *on(time.September, 30)
+	roommates := []store.Roommate{{ID: "a", Active: true, MoveIn: on(time.September, 16)}, {ID: "b", Active: true}}
+	parts := split.Participants(roommates)
+	parts[0].Fixed = 50
+	split.Prorate(parts, roommates, start, end)
+	total := money.New(5000, "USD")
+	if got, want := amounts(t, total, split.Allocate(total, parts, "bill")), []int64{2500, 2500}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
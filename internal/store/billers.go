@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// ListBillers returns the biller directory ordered by name.
+func (s *Store) ListBillers(ctx context.Context) ([]Biller, error) {
+	iter := s.client.Collection(billersCollection).Documents(ctx)
+	defer iter.Stop()
+
+	var out []Biller
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var b Biller
+		if err := doc.DataTo(&b); err != nil {
+			return nil, err
+		}
+		b.ID = doc.Ref.ID
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// GetBiller returns one directory entry.
+func (s *Store) GetBiller(ctx context.Context, id string) (*Biller, error) {
+	doc, err := s.client.Collection(billersCollection).Doc(id).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var b Biller
+	if err := doc.DataTo(&b); err != nil {
+		return nil, err
+	}
+	b.ID = doc.Ref.ID
+	return &b, nil
+}
+
+// SaveBiller creates (empty ID) or replaces a directory entry.
+func (s *Store) SaveBiller(ctx context.Context, b *Biller) error {
+	col := s.client.Collection(billersCollection)
+	if b.ID == "" {
+		b.ID = col.NewDoc().ID
+	}
+	b.UpdatedAt = time.Now()
+	_, err := col.Doc(b.ID).Set(ctx, b)
+	return err
+}
+
+// DeleteBiller removes a directory entry. Bills already linked to it keep their name.
+func (s *Store) DeleteBiller(ctx context.Context, id string) error {
+	_, err := s.client.Collection(billersCollection).Doc(id).Delete(ctx)
+	return err
+}
+
+// ListBillsSince returns bills received at or after since, oldest first.
+func (s *Store) ListBillsSince(ctx context.Context, since time.Time) ([]Bill, error) {
+	iter := s.client.Collection(billsCollection).
+		Where("dateReceived", ">=", since).
+		OrderBy("dateReceived", firestore.Asc).
+		Documents(ctx)
+	defer iter.Stop()
+
+	var out []Bill
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		b, err := billFromDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *b)
+	}
+	return out, nil
+}
@@ -2,9 +2,12 @@ package store
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/akksell/rbn/internal/money"
+	"google.golang.org/api/iterator"
 )
 
 // SaveBill creates or updates a bill and its debts subcollection (idempotent by gmailMessageId).
@@ -24,17 +27,43 @@ func (s *Store) SaveBill(ctx context.Context, bill *Bill, debts []Debt) error {
 	bill.ID = docID
 
 	data := map[string]interface{}{
-		"billerCompany":   bill.BillerCompany,
-		"totalAmount":     bill.TotalAmount,
-		"status":          bill.Status,
-		"dueDate":         bill.DueDate,
-		"dateReceived":    bill.DateReceived,
-		"gmailMessageId":  bill.GmailMessageID,
-		"currency":        bill.Currency,
-		"createdAt":       bill.CreatedAt,
+		"billerCompany":  bill.BillerCompany,
+		"totalAmount":    bill.TotalAmount,
+		"status":         bill.Status,
+		"dueDate":        bill.DueDate,
+		"dateReceived":   bill.DateReceived,
+		"gmailMessageId": bill.GmailMessageID,
+		"createdAt":      bill.CreatedAt,
+		"confidence":     bill.Confidence,
+		"evidence":       bill.Evidence,
+		"excerpt":        bill.Excerpt,
+		"accountNumber":  bill.AccountNumber,
+		"serviceAddress": bill.ServiceAddress,
+		"household":      bill.Household,
+		"billerId":       bill.BillerID,
+		"category":       bill.Category,
+		"kind":           bill.Kind,
+		"billingPeriod":  bill.BillingPeriod,
+		"periodStart":    bill.PeriodStart,
+		"periodEnd":      bill.PeriodEnd,
+		"usage":          bill.Usage,
+		"lineItems":      bill.LineItems,
+		"reviewReasons":  bill.ReviewReasons,
+	}
+	if bill.ForwardedBy != "" {
+		data["forwardedBy"] = bill.ForwardedBy
+	}
+	if bill.Supersedes != "" {
+		data["supersedes"] = bill.Supersedes
+	}
+	if bill.BillerPaidAt != nil {
+		data["billerPaidAt"] = *bill.BillerPaidAt
+		data["paymentMessageId"] = bill.PaymentMessage
 	}
 
-	_, err := ref.Set(ctx, data)
+	// Merge so a re-save (e.g. approving a held bill) keeps fields written
+	// elsewhere: relatedMessageIds, supersededBy and correctedAt.
+	_, err := ref.Set(ctx, data, firestore.MergeAll)
 	if err != nil {
 		return err
 	}
@@ -46,6 +75,8 @@ func (s *Store) SaveBill(ctx context.Context, bill *Bill, debts []Debt) error {
 			"roommateId": d.RoommateID,
 			"amount":     d.Amount,
 			"status":     d.Status,
+			"paidAmount": d.PaidAmount,
+			"rounding":   d.Rounding,
 		}
 		if d.PaidAt != nil {
 			debtData["paidAt"] = *d.PaidAt
@@ -61,6 +92,64 @@ func (s *Store) SaveBill(ctx context.Context, bill *Bill, debts []Debt) error {
 	return nil
 }
 
+// GetBill returns the bill with the given document ID.
+func (s *Store) GetBill(ctx context.Context, billID string) (*Bill, error) {
+	doc, err := s.client.Collection(billsCollection).Doc(billID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return billFromDoc(doc)
+}
+
+// ListBillsByStatus returns bills with the given status, oldest first.
+func (s *Store) ListBillsByStatus(ctx context.Context, status string) ([]Bill, error) {
+	iter := s.client.Collection(billsCollection).
+		Where("status", "==", status).
+		Documents(ctx)
+	defer iter.Stop()
+
+	var out []Bill
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		b, err := billFromDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DateReceived.Before(out[j].DateReceived) })
+	return out, nil
+}
+
+// CorrectBill overwrites the total and/or due date of a bill during review.
+// Nil values are left unchanged.
+func (s *Store) CorrectBill(ctx context.Context, billID string, total *money.Money, dueDate *time.Time) error {
+	updates := []firestore.Update{{Path: "correctedAt", Value: time.Now()}}
+	if total != nil {
+		updates = append(updates, firestore.Update{Path: "totalAmount", Value: *total})
+	}
+	if dueDate != nil {
+		updates = append(updates, firestore.Update{Path: "dueDate", Value: *dueDate})
+	}
+	_, err := s.client.Collection(billsCollection).Doc(billID).Update(ctx, updates)
+	return err
+}
+
+func billFromDoc(doc *firestore.DocumentSnapshot) (*Bill, error) {
+	var b Bill
+	if err := doc.DataTo(&b); err != nil {
+		return nil, err
+	}
+	b.ID = doc.Ref.ID
+	return &b, nil
+}
+
 // GetBillByGmailMessageID returns a bill doc ref if one exists with that gmailMessageId.
 func (s *Store) GetBillByGmailMessageID(ctx context.Context, gmailMessageID string) (*firestore.DocumentRef, error) {
 	iter := s.client.Collection(billsCollection).
@@ -81,10 +170,19 @@ func (s *Store) MarkDebtPaid(ctx context.Context, billID, roommateID string, pai
 	billRef := s.client.Collection(billsCollection).Doc(billID)
 	debtRef := billRef.Collection("debts").Doc(roommateID)
 
-	_, err := debtRef.Update(ctx, []firestore.Update{
+	doc, err := debtRef.Get(ctx)
+	if err != nil {
+		return err
+	}
+	var d Debt
+	if err := doc.DataTo(&d); err != nil {
+		return err
+	}
+	_, err = debtRef.Update(ctx, []firestore.Update{
 		{Path: "status", Value: DebtStatusPaid},
 		{Path: "paidAt", Value: paidAt},
 		{Path: "paidBy", Value: paidBy},
+		{Path: "paidAmount", Value: d.Amount},
 	})
 	if err != nil {
 		return err
@@ -94,7 +192,16 @@ func (s *Store) MarkDebtPaid(ctx context.Context, billID, roommateID string, pai
 }
 
 // recomputeBillStatus reads all debts for the bill and sets bill.status to unpaid/partial/paid.
+// Superseded bills keep their status.
 func (s *Store) recomputeBillStatus(ctx context.Context, billRef *firestore.DocumentRef) error {
+	billDoc, err := billRef.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if billDoc.Data()["status"] == BillStatusSuperseded {
+		return nil
+	}
+
 	iter := billRef.Collection("debts").Documents(ctx)
 	defer iter.Stop()
 
@@ -117,6 +224,73 @@ func (s *Store) recomputeBillStatus(ctx context.Context, billRef *firestore.Docu
 		status = BillStatusPartial
 	}
 
-	_, err := billRef.Update(ctx, []firestore.Update{{Path: "status", Value: status}})
+	_, err = billRef.Update(ctx, []firestore.Update{{Path: "status", Value: status}})
+	return err
+}
+
+// ListBillerUnpaid returns split bills the biller has not yet confirmed as paid, oldest first.
+func (s *Store) ListBillerUnpaid(ctx context.Context) ([]Bill, error) {
+	var out []Bill
+	for _, status := range []string{BillStatusUnpaid, BillStatusPartial, BillStatusPaid} {
+		bills, err := s.ListBillsByStatus(ctx, status)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bills {
+			if b.BillerPaidAt == nil {
+				out = append(out, b)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DateReceived.Before(out[j].DateReceived) })
+	return out, nil
+}
+
+// MarkBillerPaid records that the biller confirmed payment of a bill in the given message.
+func (s *Store) MarkBillerPaid(ctx context.Context, billID string, paidAt time.Time, gmailMessageID string) error {
+	_, err := s.client.Collection(billsCollection).Doc(billID).Update(ctx, []firestore.Update{
+		{Path: "billerPaidAt", Value: paidAt},
+		{Path: "paymentMessageId", Value: gmailMessageID},
+	})
+	return err
+}
+
+// ListDebts returns the debts of a bill.
+func (s *Store) ListDebts(ctx context.Context, billID string) ([]Debt, error) {
+	iter := s.client.Collection(billsCollection).Doc(billID).Collection("debts").Documents(ctx)
+	defer iter.Stop()
+
+	var out []Debt
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var d Debt
+		if err := doc.DataTo(&d); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// AddRelatedMessage links another Gmail message (a reminder or duplicate) to an existing bill.
+func (s *Store) AddRelatedMessage(ctx context.Context, billID, gmailMessageID string) error {
+	_, err := s.client.Collection(billsCollection).Doc(billID).Update(ctx, []firestore.Update{
+		{Path: "relatedMessageIds", Value: firestore.ArrayUnion(gmailMessageID)},
+	})
+	return err
+}
+
+// SupersedeBill marks a bill as replaced by a corrected statement.
+func (s *Store) SupersedeBill(ctx context.Context, billID, correctedID string) error {
+	_, err := s.client.Collection(billsCollection).Doc(billID).Update(ctx, []firestore.Update{
+		{Path: "status", Value: BillStatusSuperseded},
+		{Path: "supersededBy", Value: correctedID},
+	})
 	return err
 }
@@ -0,0 +1,88 @@
+package store_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akksell/rbn/internal/money"
+	"github.com/akksell/rbn/internal/store"
+)
+
+// newStore returns a Store backed by the Firestore emulator, skipping the
+// test if FIRESTORE_EMULATOR_HOST is not set.
+func newStore(t *testing.T) (*store.Store, *firestore.Client) {
+	t.Helper()
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST not set")
+	}
+	client, err := firestore.NewClient(context.Background(), "rbn-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return store.New(client), client
+}
+
+func TestSaveBillKeepsLinks(t *testing.T) {
+	st, client := newStore(t)
+	ctx := context.Background()
+	id := "keeps-links-" + time.Now().Format("150405.000000000")
+
+	// A held bill gets a reminder linked, a review correction and a
+	// supersededBy link, all written outside SaveBill. Re-saving it with
+	// debts, as approveBill does, must keep them.
+	held := &store.Bill{
+		GmailMessageID: id,
+		BillerCompany:  "Bay Electric",
+		TotalAmount:    money.New(9817, "USD"),
+		Status:         store.BillStatusNeedsReview,
+		DateReceived:   time.Now(),
+	}
+	if err := st.SaveBill(ctx, held, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.AddRelatedMessage(ctx, id, "reminder-1"); err != nil {
+		t.Fatal(err)
+	}
+	total := money.New(9917, "USD")
+	if err := st.CorrectBill(ctx, id, &total, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SupersedeBill(ctx, id, "corrected-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := st.GetBill(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Status = store.BillStatusUnpaid
+	debts := []store.Debt{{RoommateID: "a", Amount: total, Status: store.DebtStatusPending}}
+	if err := st.SaveBill(ctx, b, debts); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := client.Collection("bills").Doc(id).Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.GetBill(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Related) != 1 || got.Related[0] != "reminder-1" {
+		t.Errorf("related = %v, want [reminder-1]", got.Related)
+	}
+	if got.SupersededBy != "corrected-1" {
+		t.Errorf("supersededBy = %q, want corrected-1", got.SupersededBy)
+	}
+	if _, err := doc.DataAt("correctedAt"); err != nil {
+		t.Errorf("correctedAt: %v", err)
+	}
+	if got.TotalAmount != total {
+		t.Errorf("total = %v, want %v", got.TotalAmount, total)
+	}
+}
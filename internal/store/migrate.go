@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/akksell/rbn/internal/money"
+	"google.golang.org/api/iterator"
+)
+
+// legacyCurrency is assumed for bills saved before amounts carried a currency.
+const legacyCurrency = "USD"
+
+// MigrateMoney rewrites bills and debts saved with float64 amounts to
+// money.Money values in minor units. Documents already migrated are skipped,
+// so it is safe to run more than once. It returns the number of bills changed.
+func (s *Store) MigrateMoney(ctx context.Context) (int, error) {
+	iter := s.client.Collection(billsCollection).Documents(ctx)
+	defer iter.Stop()
+
+	migrated := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return migrated, err
+		}
+		data := doc.Data()
+		currency, _ := data["currency"].(string)
+		if currency == "" {
+			currency = legacyCurrency
+		}
+
+		changed, err := migrateDebts(ctx, doc.Ref, currency)
+		if err != nil {
+			return migrated, fmt.Errorf("bill %s debts: %w", doc.Ref.ID, err)
+		}
+		if total, ok := legacyAmount(data["totalAmount"], currency); ok {
+			if _, err := doc.Ref.Update(ctx, []firestore.Update{
+				{Path: "totalAmount", Value: total},
+				{Path: "currency", Value: firestore.Delete},
+			}); err != nil {
+				return migrated, fmt.Errorf("bill %s: %w", doc.Ref.ID, err)
+			}
+			changed = true
+		}
+		if changed {
+			migrated++
+		}
+	}
+	return migrated, nil
+}
+
+func migrateDebts(ctx context.Context, billRef *firestore.DocumentRef, currency string) (bool, error) {
+	iter := billRef.Collection("debts").Documents(ctx)
+	defer iter.Stop()
+
+	changed := false
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return changed, err
+		}
+		amount, ok := legacyAmount(doc.Data()["amount"], currency)
+		if !ok {
+			continue
+		}
+		if _, err := doc.Ref.Update(ctx, []firestore.Update{{Path: "amount", Value: amount}}); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// legacyAmount converts a numeric Firestore value to money; ok is false if v
+// is not a number (already migrated or missing).
+func legacyAmount(v interface{}, currency string) (money.Money, bool) {
+	switch n := v.(type) {
+	case float64:
+		return money.FromFloat(n, currency), true
+	case int64:
+		return money.FromFloat(float64(n), currency), true
+	}
+	return money.Money{}, false
+}
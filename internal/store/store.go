@@ -14,6 +14,7 @@ const (
 	billsCollection     = "bills"
 	historyIDDocPath    = "gmail_history"
 	configCollection    = "config"
+	billersCollection   = "billers"
 )
 
 // Store handles Firestore access for roommates, bills, debts, and history ID.
@@ -28,6 +29,22 @@ func New(client *firestore.Client) *Store {
 
 // ListActiveRoommates returns roommates where active is true or not set.
 func (s *Store) ListActiveRoommates(ctx context.Context) ([]Roommate, error) {
+	all, err := s.ListRoommates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []Roommate
+	for _, r := range all {
+		if r.Active {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// ListRoommates returns all roommates, including those who moved out. Active
+// defaults to true when not set.
+func (s *Store) ListRoommates(ctx context.Context) ([]Roommate, error) {
 	col := s.client.Collection(roommatesCollection)
 	iter := col.Documents(ctx)
 	defer iter.Stop()
@@ -41,17 +58,13 @@ func (s *Store) ListActiveRoommates(ctx context.Context) ([]Roommate, error) {
 		if err != nil {
 			return nil, err
 		}
-		data := doc.Data()
-		if v, ok := data["active"].(bool); ok && !v {
-			continue
-		}
 		var r Roommate
 		if err := doc.DataTo(&r); err != nil {
 			continue
 		}
 		r.ID = doc.Ref.ID
 		r.Active = true
-		if v, ok := data["active"].(bool); ok {
+		if v, ok := doc.Data()["active"].(bool); ok {
 			r.Active = v
 		}
 		out = append(out, r)
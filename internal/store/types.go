@@ -2,6 +2,8 @@ package store
 
 import (
 	"time"
+
+	"github.com/akksell/rbn/internal/money"
 )
 
 // Roommate represents a roommate document from the roommates collection.
@@ -10,30 +12,151 @@ type Roommate struct {
 	Email       string `firestore:"email"`
 	DisplayName string `firestore:"displayName"`
 	Active      bool   `firestore:"active"`
+	Household   string `firestore:"household"` // optional; bills routed to a household split only among its members
+	// MoveIn and MoveOut bound the days the roommate lived there, inclusive;
+	// nil means before and after every bill. Shares of bills whose service
+	// period overlaps either date are prorated.
+	MoveIn  *time.Time `firestore:"moveIn"`
+	MoveOut *time.Time `firestore:"moveOut"`
+
+	// Weight and Percent size the roommate's share of every bill; see Share.
+	Weight  float64 `firestore:"weight"`
+	Percent float64 `firestore:"percent"`
+	// BillerShares overrides Weight and Percent for some billers, keyed by
+	// biller directory ID or biller name.
+	BillerShares map[string]Share `firestore:"billerShares"`
+	// OptOut lists biller directory IDs, biller names or categories whose
+	// bills the roommate is not part of, e.g. "gas".
+	OptOut []string `firestore:"optOut"`
+}
+
+// Share sizes a roommate's part of a bill. A positive Fixed charges that
+// amount; a positive Percent takes that percentage of the total; the rest is
+// divided among the other roommates in proportion to Weight, where 0 counts
+// as 1. Cap and Floor, when positive, bound the resulting share. Amounts are
+// in major units of the bill's currency.
+type Share struct {
+	Weight  float64 `firestore:"weight" json:"weight,omitempty"`
+	Percent float64 `firestore:"percent" json:"percent,omitempty"`
+	Fixed   float64 `firestore:"fixed" json:"fixed,omitempty"`
+	Cap     float64 `firestore:"cap" json:"cap,omitempty"`
+	Floor   float64 `firestore:"floor" json:"floor,omitempty"`
 }
 
 // Bill represents a bill document in the bills collection.
 type Bill struct {
-	ID              string    `firestore:"-"` // document ID, set from Ref
-	BillerCompany   string    `firestore:"billerCompany"`
-	TotalAmount     float64   `firestore:"totalAmount"`
-	Status          string    `firestore:"status"` // unpaid, partial, paid (derived)
-	DueDate         time.Time `firestore:"dueDate"`
-	DateReceived    time.Time `firestore:"dateReceived"`
-	GmailMessageID  string    `firestore:"gmailMessageId"`
-	Currency        string    `firestore:"currency"`
-	CreatedAt       time.Time `firestore:"createdAt"`
+	ID             string      `firestore:"-" json:"id"` // document ID, set from Ref
+	BillerCompany  string      `firestore:"billerCompany" json:"billerCompany"`
+	TotalAmount    money.Money `firestore:"totalAmount" json:"totalAmount"`
+	Status         string      `firestore:"status" json:"status"` // needs_review, unpaid, partial, paid (derived)
+	DueDate        time.Time   `firestore:"dueDate" json:"dueDate"`
+	DateReceived   time.Time   `firestore:"dateReceived" json:"dateReceived"`
+	GmailMessageID string      `firestore:"gmailMessageId" json:"gmailMessageId"`
+	CreatedAt      time.Time   `firestore:"createdAt" json:"createdAt"`
+	Confidence     float64     `firestore:"confidence" json:"confidence"` // extraction confidence, 0-1
+	Evidence       []Evidence  `firestore:"evidence" json:"evidence"`
+	Excerpt        string      `firestore:"excerpt" json:"excerpt"`             // message text included in notifications
+	AccountNumber  string      `firestore:"accountNumber" json:"accountNumber"` // masked, e.g. ****4821
+	ServiceAddress string      `firestore:"serviceAddress" json:"serviceAddress"`
+	Household      string      `firestore:"household" json:"household"` // routed household, empty for all roommates
+	BillerID       string      `firestore:"billerId" json:"billerId"`   // biller directory entry, if the sender is known
+	Category       string      `firestore:"category" json:"category"`
+	Kind           string      `firestore:"kind" json:"kind"`                                             // bill, reminder or correction (bill.Kind)
+	BillerPaidAt   *time.Time  `firestore:"billerPaidAt,omitempty" json:"billerPaidAt,omitempty"`         // when the biller confirmed payment
+	PaymentMessage string      `firestore:"paymentMessageId,omitempty" json:"paymentMessageId,omitempty"` // Gmail ID of the confirmation
+	BillingPeriod  string      `firestore:"billingPeriod" json:"billingPeriod"`
+	ReviewReasons  []string    `firestore:"reviewReasons" json:"reviewReasons,omitempty"`       // why the bill is held for review
+	ForwardedBy    string      `firestore:"forwardedBy,omitempty" json:"forwardedBy,omitempty"` // roommate ID (or address) who forwarded the bill
+	PeriodStart    time.Time   `firestore:"periodStart" json:"periodStart"`                     // zero if the bill has no service period
+	PeriodEnd      time.Time   `firestore:"periodEnd" json:"periodEnd"`
+	Usage          []Usage     `firestore:"usage" json:"usage"`
+	LineItems      []LineItem  `firestore:"lineItems" json:"lineItems"`
+	Related        []string    `firestore:"relatedMessageIds" json:"relatedMessageIds"`           // reminders and duplicates of this bill
+	Supersedes     string      `firestore:"supersedes,omitempty" json:"supersedes,omitempty"`     // bill this corrected statement replaces
+	SupersededBy   string      `firestore:"supersededBy,omitempty" json:"supersededBy,omitempty"` // corrected statement replacing this bill
+}
+
+// Biller is an entry in the biller directory (billers collection). It maps
+// sender addresses and domains to a canonical biller name.
+type Biller struct {
+	ID              string    `firestore:"-" json:"id"` // document ID, set from Ref
+	Name            string    `firestore:"name" json:"name"`
+	Senders         []string  `firestore:"senders" json:"senders"`                 // full addresses or domains, e.g. "pge.com"
+	Category        string    `firestore:"category" json:"category"`               // e.g. electric, internet, water
+	PayerRoommateID string    `firestore:"payerRoommateId" json:"payerRoommateId"` // roommate who pays the biller
+	Autopay         bool      `firestore:"autopay" json:"autopay"`
+	Cadence         string    `firestore:"cadence" json:"cadence"` // monthly, bimonthly, quarterly, annual
+	UpdatedAt       time.Time `firestore:"updatedAt" json:"updatedAt"`
+}
+
+// Billing cadences for Biller.Cadence.
+const (
+	CadenceMonthly   = "monthly"
+	CadenceBimonthly = "bimonthly"
+	CadenceQuarterly = "quarterly"
+	CadenceAnnual    = "annual"
+)
+
+// CadenceMonths returns the number of months between bills for a cadence, or 0 if unknown.
+func CadenceMonths(cadence string) int {
+	switch cadence {
+	case CadenceMonthly:
+		return 1
+	case CadenceBimonthly:
+		return 2
+	case CadenceQuarterly:
+		return 3
+	case CadenceAnnual:
+		return 12
+	}
+	return 0
+}
+
+// LineItem is one itemized charge on a bill.
+type LineItem struct {
+	Description string      `firestore:"description" json:"description"`
+	Amount      money.Money `firestore:"amount" json:"amount"`
+	RoommateIDs []string    `firestore:"roommateIds" json:"roommateIds,omitempty"` // who shares it; empty means everyone
+}
+
+// Usage is a metered quantity from a bill, e.g. 412 kWh.
+type Usage struct {
+	Quantity float64 `firestore:"quantity" json:"quantity"`
+	Unit     string  `firestore:"unit" json:"unit"`
+}
+
+// Evidence is the email text an extracted bill field was read from.
+type Evidence struct {
+	Field  string `firestore:"field" json:"field"`
+	Text   string `firestore:"text" json:"text"`
+	Source string `firestore:"source" json:"source"`
 }
 
 // Debt represents a roommate's debt for a bill (bills/{billId}/debts).
 type Debt struct {
-	RoommateID string     `firestore:"roommateId"`
-	Amount     float64    `firestore:"amount"`
-	Status     string     `firestore:"status"` // pending, paid
-	PaidAt     *time.Time `firestore:"paidAt,omitempty"`
-	PaidBy     string     `firestore:"paidBy,omitempty"`
+	RoommateID string      `firestore:"roommateId"`
+	Amount     money.Money `firestore:"amount"`
+	Status     string      `firestore:"status"` // pending, paid
+	PaidAt     *time.Time  `firestore:"paidAt,omitempty"`
+	PaidBy     string      `firestore:"paidBy,omitempty"`
+	PaidAmount money.Money `firestore:"paidAmount"` // paid so far; carried over when a bill is corrected
+	Rounding   money.Money `firestore:"rounding"`   // minor units added to the exact share to make the debts add up
+}
+
+// Owed returns what is still owed on the debt (negative if overpaid).
+func (d Debt) Owed() money.Money {
+	if d.Status == DebtStatusPaid && d.PaidAmount.IsZero() {
+		return money.New(0, d.Amount.Currency)
+	}
+	return d.Amount.Sub(d.PaidAmount)
 }
 
+// BillStatusNeedsReview is the status of a bill held for manual review; it has no debts yet.
+const BillStatusNeedsReview = "needs_review"
+
+// BillStatusSuperseded is the status of a bill replaced by a corrected statement; its debts no longer count.
+const BillStatusSuperseded = "superseded"
+
 // BillStatusUnpaid is the derived status when no roommate has paid.
 const BillStatusUnpaid = "unpaid"
 
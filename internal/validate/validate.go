@@ -0,0 +1,86 @@
+// Package validate checks extracted bill amounts before they are split.
+package validate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/akksell/rbn/internal/config"
+	"github.com/akksell/rbn/internal/money"
+	"github.com/akksell/rbn/internal/store"
+)
+
+const (
+	defaultHistoryBills = 6
+	defaultMinHistory   = 3
+)
+
+// Check returns the reasons b's amount looks wrong, or nil if it passes.
+// history holds the biller's earlier bills, oldest first; only those in
+// b's currency are averaged.
+func Check(spec config.ValidationSpec, b *store.Bill, history []store.Bill) []string {
+	var reasons []string
+	amount := b.TotalAmount
+	if amount.Minor <= 0 {
+		return append(reasons, fmt.Sprintf("amount %s is not positive", amount.Format()))
+	}
+	limits := limitsFor(spec, amount.Currency)
+	if min := money.FromFloat(limits.Min, amount.Currency); limits.Min > 0 && amount.Minor < min.Minor {
+		reasons = append(reasons, fmt.Sprintf("amount %s is below the minimum of %s", amount.Format(), min.Format()))
+	}
+	if max := money.FromFloat(limits.Max, amount.Currency); limits.Max > 0 && amount.Minor > max.Minor {
+		reasons = append(reasons, fmt.Sprintf("amount %s is above the maximum of %s", amount.Format(), max.Format()))
+	}
+	if spec.MaxDeviation > 0 {
+		if reason := deviation(spec, amount, history); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// limitsFor returns the configured limits for currency, or none.
+func limitsFor(spec config.ValidationSpec, currency string) config.AmountLimits {
+	for code, l := range spec.Limits {
+		if strings.EqualFold(code, currency) {
+			return l
+		}
+	}
+	return config.AmountLimits{}
+}
+
+// deviation compares amount with the average of the most recent bills.
+func deviation(spec config.ValidationSpec, amount money.Money, history []store.Bill) string {
+	n, minHistory := spec.HistoryBills, spec.MinHistory
+	if n <= 0 {
+		n = defaultHistoryBills
+	}
+	if minHistory <= 0 {
+		minHistory = defaultMinHistory
+	}
+
+	var sum int64
+	count := 0
+	for i := len(history) - 1; i >= 0 && count < n; i-- {
+		if history[i].TotalAmount.Currency != amount.Currency || history[i].TotalAmount.Minor <= 0 {
+			continue
+		}
+		sum += history[i].TotalAmount.Minor
+		count++
+	}
+	if count < minHistory {
+		return ""
+	}
+	avg := float64(sum) / float64(count)
+	change := (float64(amount.Minor) - avg) / avg
+	if math.Abs(change) <= spec.MaxDeviation {
+		return ""
+	}
+	direction := "above"
+	if change < 0 {
+		direction = "below"
+	}
+	return fmt.Sprintf("amount %s is %.0f%% %s the average of %s over the last %d bills",
+		amount.Format(), math.Abs(change)*100, direction, money.New(int64(math.Round(avg)), amount.Currency).Format(), count)
+}